@@ -2,8 +2,13 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"github.com/yanw2/go-by-example/atomic-counters/metrics"
+	"github.com/yanw2/go-by-example/atomic-counters/striped"
 )
 
 func main() {
@@ -37,4 +42,58 @@ func main() {
 	// It's safe to access ops now because we know no other goroutine is writing to it. Reading aotmics safely
 	// while they are being updated is also possible, using functions like `atomic.LoadUint64`.
 	fmt.Println("ops:", ops)
+
+	named()
+	contended()
+}
+
+// A bare `uint64` works for one counter, but a program usually tracks many values and wants to
+// report them. The `metrics` package wraps atomics in named counters, gauges and histograms, keeps
+// them in a registry, and writes them out in the Prometheus text format.
+func named() {
+	reg := metrics.NewRegistry()
+	ops := reg.Counter("ops_total", "Operations performed.")
+	inFlight := reg.Gauge("ops_in_flight", "Operations currently running.")
+	latency := reg.Histogram("op_duration_seconds", "Time taken by each operation.",
+		[]float64{.001, .01, .1})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := 0; c < 1000; c++ {
+				inFlight.Inc()
+				start := time.Now()
+				ops.Inc()
+				latency.Observe(time.Since(start).Seconds())
+				inFlight.Dec()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Asking the registry for a name it already has returns the same metric.
+	fmt.Println("ops:", reg.Counter("ops_total", "").Value())
+	reg.WritePrometheus(os.Stdout)
+}
+
+// With many CPUs incrementing `ops` at once, every increment fights over the one cache line holding
+// it. A `striped.Counter` spreads increments over a cell per CPU and sums the cells when read. It is
+// used just like `ops` above. The benchmarks in `striped/striped_test.go` compare the two; run them
+// with `go test -bench . -cpu 1,2,4,8 ./atomic-counters/striped` to see how they scale.
+func contended() {
+	ops := striped.New()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := 0; c < 1000; c++ {
+				ops.Inc()
+			}
+		}()
+	}
+	wg.Wait()
+	fmt.Println("striped ops:", ops.Load())
 }
@@ -0,0 +1,250 @@
+// Package metrics provides named counters, gauges and histograms backed by atomics, as in the
+// atomic-counters example, together with a registry that keeps track of them and renders them in the
+// Prometheus text exposition format. Updating a metric never takes a lock.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Kind is the type of a metric.
+type Kind int
+
+// The kinds of metric, named as in the Prometheus TYPE line.
+const (
+	KindCounter Kind = iota
+	KindGauge
+	KindHistogram
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindCounter:
+		return "counter"
+	case KindGauge:
+		return "gauge"
+	case KindHistogram:
+		return "histogram"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Metric is implemented by *Counter, *Gauge and *Histogram.
+type Metric interface {
+	Name() string
+	Help() string
+	Kind() Kind
+}
+
+type desc struct {
+	name, help string
+}
+
+func (d *desc) Name() string { return d.name }
+func (d *desc) Help() string { return d.help }
+
+// Counter is a count that only goes up, such as the number of requests served.
+type Counter struct {
+	desc
+	v atomic.Uint64
+}
+
+func (c *Counter) Kind() Kind { return KindCounter }
+
+// Inc adds one to the counter.
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+// Add adds n to the counter.
+func (c *Counter) Add(n uint64) {
+	c.v.Add(n)
+}
+
+// Value returns the current count.
+func (c *Counter) Value() uint64 {
+	return c.v.Load()
+}
+
+// Gauge is a value that can go up and down, such as the number of requests in flight.
+type Gauge struct {
+	desc
+	// bits holds the math.Float64bits of the value, since there is no atomic float64.
+	bits atomic.Uint64
+}
+
+func (g *Gauge) Kind() Kind { return KindGauge }
+
+// Set sets the gauge to v.
+func (g *Gauge) Set(v float64) {
+	g.bits.Store(math.Float64bits(v))
+}
+
+// Add adds delta, which may be negative, to the gauge.
+func (g *Gauge) Add(delta float64) {
+	addFloat(&g.bits, delta)
+}
+
+// Inc adds one to the gauge.
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+// Dec subtracts one from the gauge.
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+// Value returns the current value.
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(g.bits.Load())
+}
+
+// addFloat adds delta to the float64 stored as bits in u. Other goroutines may change u between the
+// load and the store, so it retries until its compare-and-swap wins.
+func addFloat(u *atomic.Uint64, delta float64) {
+	for {
+		old := u.Load()
+		if u.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+// DefaultBuckets are the histogram bucket bounds used when none are given. They suit latencies
+// measured in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Histogram counts observations, such as request latencies, in buckets by value.
+type Histogram struct {
+	desc
+	bounds []float64
+	// counts[i] counts the observations in (bounds[i-1], bounds[i]], and the last element counts
+	// the ones above every bound.
+	counts []atomic.Uint64
+	sum    atomic.Uint64
+}
+
+func (h *Histogram) Kind() Kind { return KindHistogram }
+
+// Observe records v.
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+	h.counts[i].Add(1)
+	addFloat(&h.sum, v)
+}
+
+// HistogramSnapshot is a copy of a histogram's state. Counts are cumulative, as in the Prometheus
+// format: Counts[i] is the number of observations less than or equal to Bounds[i].
+type HistogramSnapshot struct {
+	Bounds []float64
+	Counts []uint64
+	Count  uint64
+	Sum    float64
+}
+
+// Snapshot returns the current state of the histogram. Count is always consistent with Counts, but
+// if observations are being made meanwhile Sum may be slightly ahead of or behind them.
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	s := HistogramSnapshot{
+		Bounds: slices.Clone(h.bounds),
+		Counts: make([]uint64, len(h.bounds)),
+	}
+	var cum uint64
+	for i := range h.bounds {
+		cum += h.counts[i].Load()
+		s.Counts[i] = cum
+	}
+	s.Count = cum + h.counts[len(h.bounds)].Load()
+	s.Sum = math.Float64frombits(h.sum.Load())
+	return s
+}
+
+var validName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// Registry holds a set of uniquely named metrics. It is safe for concurrent use.
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]Metric
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]Metric)}
+}
+
+// Counter returns the counter with the given name, registering it if it doesn't exist yet. It panics
+// if the name is not a valid Prometheus metric name or belongs to a metric of another kind.
+func (r *Registry) Counter(name, help string) *Counter {
+	return register(r, name, KindCounter, func() *Counter {
+		return &Counter{desc: desc{name, help}}
+	})
+}
+
+// Gauge returns the gauge with the given name, registering it if it doesn't exist yet. It panics in
+// the same cases as Counter.
+func (r *Registry) Gauge(name, help string) *Gauge {
+	return register(r, name, KindGauge, func() *Gauge {
+		return &Gauge{desc: desc{name, help}}
+	})
+}
+
+// Histogram returns the histogram with the given name, registering it with the given bucket bounds if
+// it doesn't exist yet. nil bounds mean DefaultBuckets. It panics in the same cases as Counter, and if
+// the bounds are not in increasing order.
+func (r *Registry) Histogram(name, help string, bounds []float64) *Histogram {
+	if bounds == nil {
+		bounds = DefaultBuckets
+	}
+	for i := 1; i < len(bounds); i++ {
+		if bounds[i] <= bounds[i-1] {
+			panic(fmt.Sprintf("metrics: histogram %q: bucket bounds not increasing", name))
+		}
+	}
+	return register(r, name, KindHistogram, func() *Histogram {
+		return &Histogram{
+			desc:   desc{name, help},
+			bounds: slices.Clone(bounds),
+			counts: make([]atomic.Uint64, len(bounds)+1),
+		}
+	})
+}
+
+func register[M Metric](r *Registry, name string, kind Kind, create func() M) M {
+	if !validName.MatchString(name) {
+		panic(fmt.Sprintf("metrics: invalid metric name %q", name))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, ok := r.metrics[name]; ok {
+		if m.Kind() != kind {
+			panic(fmt.Sprintf("metrics: %q already registered as a %s", name, m.Kind()))
+		}
+		return m.(M)
+	}
+	m := create()
+	r.metrics[name] = m
+	return m
+}
+
+// Metrics returns the registered metrics, sorted by name.
+func (r *Registry) Metrics() []Metric {
+	r.mu.Lock()
+	ms := make([]Metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		ms = append(ms, m)
+	}
+	r.mu.Unlock()
+
+	slices.SortFunc(ms, func(a, b Metric) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return ms
+}
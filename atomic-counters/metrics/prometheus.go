@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// WritePrometheus writes every metric in the registry to w in the Prometheus text exposition format,
+// sorted by name.
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, m := range r.Metrics() {
+		if m.Help() != "" {
+			bw.WriteString("# HELP " + m.Name() + " " + helpEscaper.Replace(m.Help()) + "\n")
+		}
+		bw.WriteString("# TYPE " + m.Name() + " " + m.Kind().String() + "\n")
+
+		switch m := m.(type) {
+		case *Counter:
+			bw.WriteString(m.Name() + " " + strconv.FormatUint(m.Value(), 10) + "\n")
+		case *Gauge:
+			bw.WriteString(m.Name() + " " + formatFloat(m.Value()) + "\n")
+		case *Histogram:
+			s := m.Snapshot()
+			for i, bound := range s.Bounds {
+				bw.WriteString(m.Name() + `_bucket{le="` + formatFloat(bound) + `"} ` +
+					strconv.FormatUint(s.Counts[i], 10) + "\n")
+			}
+			count := strconv.FormatUint(s.Count, 10)
+			bw.WriteString(m.Name() + `_bucket{le="+Inf"} ` + count + "\n")
+			bw.WriteString(m.Name() + "_sum " + formatFloat(s.Sum) + "\n")
+			bw.WriteString(m.Name() + "_count " + count + "\n")
+		}
+	}
+	// bufio.Writer remembers the first write error, so checking once at the end is enough.
+	return bw.Flush()
+}
+
+// helpEscaper escapes help text as the format requires.
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,60 @@
+// Package striped provides a counter for hot paths that many goroutines increment at once.
+//
+// When every goroutine adds to the same word, as the atomic-counters example does, each increment has
+// to take exclusive ownership of that word's cache line, so the line bounces between CPUs and the
+// increments queue up behind each other. A striped counter spreads increments over several cells,
+// each on its own cache line, and adds the cells up when it is read. Increments get cheaper under
+// contention; reads get more expensive and are not a consistent snapshot.
+package striped
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+)
+
+// cacheLine is a conservative cache line size. Some CPUs fetch lines in pairs, so 128 bytes rather
+// than 64 keeps neighbouring cells from sharing a fetch.
+const cacheLine = 128
+
+type cell struct {
+	n atomic.Uint64
+	_ [cacheLine - 8]byte
+}
+
+// Counter is a striped counter. The zero value is not usable; create one with New.
+type Counter struct {
+	cells []cell
+	mask  uint64
+}
+
+// New returns a counter with one cell per CPU, rounded up to a power of two.
+func New() *Counter {
+	n := 1
+	for n < runtime.GOMAXPROCS(0) {
+		n <<= 1
+	}
+	return &Counter{cells: make([]cell, n), mask: uint64(n - 1)}
+}
+
+// Add adds n to the counter.
+func (c *Counter) Add(n uint64) {
+	// Go doesn't expose which CPU a goroutine is running on, so pick a cell at random. The top-level
+	// math/rand/v2 functions use per-thread state, so this doesn't introduce a shared word of its own,
+	// and goroutines running at the same time rarely land on the same cell.
+	c.cells[rand.Uint64()&c.mask].n.Add(n)
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Load returns the sum of the cells. Increments made while it runs may or may not be included.
+func (c *Counter) Load() uint64 {
+	var sum uint64
+	for i := range c.cells {
+		sum += c.cells[i].n.Load()
+	}
+	return sum
+}
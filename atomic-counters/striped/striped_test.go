@@ -0,0 +1,74 @@
+package striped
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"unsafe"
+)
+
+func TestConcurrentIncrementsAreAllCounted(t *testing.T) {
+	c := New()
+	var wg sync.WaitGroup
+	for g := 0; g < 50; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				c.Inc()
+			}
+			c.Add(10)
+		}()
+	}
+	wg.Wait()
+
+	if got, want := c.Load(), uint64(50*1000+50*10); got != want {
+		t.Fatalf("Load = %d, want %d", got, want)
+	}
+}
+
+func TestCellFillsACacheLine(t *testing.T) {
+	if size := unsafe.Sizeof(cell{}); size != cacheLine {
+		t.Fatalf("cell is %d bytes, want %d", size, cacheLine)
+	}
+}
+
+// The benchmarks increment from every CPU at once; run them with something like -cpu 1,2,4,8. On a
+// single CPU there is nothing to contend over, so the plain atomic wins. The striped counter only
+// pays off once enough cores contend for the plain atomic's cache line, and how many that takes
+// depends on the machine.
+func BenchmarkAdd(b *testing.B) {
+	c := New()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Inc()
+		}
+	})
+	if c.Load() != uint64(b.N) {
+		b.Fatal("lost increments")
+	}
+}
+
+func BenchmarkAtomicAdd(b *testing.B) {
+	var ops atomic.Uint64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			ops.Add(1)
+		}
+	})
+	if ops.Load() != uint64(b.N) {
+		b.Fatal("lost increments")
+	}
+}
+
+func BenchmarkLoad(b *testing.B) {
+	c := New()
+	c.Add(1)
+	var sum uint64
+	for i := 0; i < b.N; i++ {
+		sum += c.Load()
+	}
+	if sum != uint64(b.N) {
+		b.Fatal("wrong sum")
+	}
+}
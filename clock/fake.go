@@ -0,0 +1,215 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock whose time only moves when Advance or Set is called. Timers, tickers, After and
+// Sleep fire as the fake time passes their deadlines, so time-based code can be tested without real
+// waits. It is safe for concurrent use.
+type Fake struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// fakeWaiter is a pending timer or ticker. A period of zero means it fires once.
+type fakeWaiter struct {
+	at     time.Time
+	period time.Duration
+	c      chan time.Time
+}
+
+// NewFake returns a fake clock set to the given time.
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now returns the fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance moves the fake time forward by d, firing every timer and ticker whose deadline is reached
+// along the way, in order.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.setLocked(f.now.Add(d))
+}
+
+// Set moves the fake time to t, firing timers and tickers as Advance does. Moving time backwards
+// fires nothing.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.setLocked(t)
+}
+
+func (f *Fake) setLocked(t time.Time) {
+	for {
+		var next *fakeWaiter
+		for _, w := range f.waiters {
+			if !w.at.After(t) && (next == nil || w.at.Before(next.at)) {
+				next = w
+			}
+		}
+		if next == nil {
+			break
+		}
+
+		if next.at.After(f.now) {
+			f.now = next.at
+		}
+		// Like the real ones, fake timer and ticker channels hold a single value; ticks that nobody
+		// has received yet are dropped.
+		select {
+		case next.c <- f.now:
+		default:
+		}
+		if next.period > 0 {
+			next.at = next.at.Add(next.period)
+		} else {
+			f.removeLocked(next)
+		}
+	}
+	f.now = t
+}
+
+func (f *Fake) addLocked(w *fakeWaiter) {
+	f.waiters = append(f.waiters, w)
+	f.cond.Broadcast()
+}
+
+func (f *Fake) removeLocked(w *fakeWaiter) bool {
+	for i, x := range f.waiters {
+		if x == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// BlockUntil blocks until at least n timers, tickers or sleepers are waiting on the clock. Tests use it
+// to make sure a goroutine has started waiting before they advance the time.
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) < n {
+		f.cond.Wait()
+	}
+}
+
+// After waits for the fake time to pass d and then sends the fake time on the returned channel.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).Chan()
+}
+
+// Sleep blocks until the fake time has passed d.
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+// NewTimer returns a timer that fires once the fake time has passed d.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{f: f, w: &fakeWaiter{c: make(chan time.Time, 1)}}
+	t.w.at = f.now.Add(d)
+	if d <= 0 {
+		t.w.c <- f.now
+		return t
+	}
+	f.addLocked(t.w)
+	return t
+}
+
+// NewTicker returns a ticker that fires every time the fake time passes another d. It panics if d is
+// not positive, as time.NewTicker does.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTicker{f: f, w: &fakeWaiter{at: f.now.Add(d), period: d, c: make(chan time.Time, 1)}}
+	f.addLocked(t.w)
+	return t
+}
+
+// fakeTimer and fakeTicker drain their channels on Stop and Reset so that, as with time.Timer and
+// time.Ticker since Go 1.23, a value sent before the call is never received after it.
+type fakeTimer struct {
+	f *Fake
+	w *fakeWaiter
+}
+
+func (t *fakeTimer) Chan() <-chan time.Time { return t.w.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	active := t.f.removeLocked(t.w)
+	select {
+	case <-t.w.c:
+	default:
+	}
+	return active
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	active := t.f.removeLocked(t.w)
+	select {
+	case <-t.w.c:
+	default:
+	}
+	t.w.at = t.f.now.Add(d)
+	if d <= 0 {
+		t.w.c <- t.f.now
+		return active
+	}
+	t.f.addLocked(t.w)
+	return active
+}
+
+type fakeTicker struct {
+	f *Fake
+	w *fakeWaiter
+}
+
+func (t *fakeTicker) Chan() <-chan time.Time { return t.w.c }
+
+func (t *fakeTicker) Stop() {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.removeLocked(t.w)
+	select {
+	case <-t.w.c:
+	default:
+	}
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Ticker.Reset")
+	}
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.removeLocked(t.w)
+	select {
+	case <-t.w.c:
+	default:
+	}
+	t.w.at = t.f.now.Add(d)
+	t.w.period = d
+	t.f.addLocked(t.w)
+}
@@ -0,0 +1,210 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ready reports whether c has a value waiting, taking it if so.
+func ready(c <-chan time.Time) (time.Time, bool) {
+	select {
+	case t := <-c:
+		return t, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func TestFakeAdvanceAndSet(t *testing.T) {
+	f := NewFake(epoch)
+	f.Advance(time.Minute)
+	if got := f.Now(); !got.Equal(epoch.Add(time.Minute)) {
+		t.Fatalf("Now after Advance = %v, want %v", got, epoch.Add(time.Minute))
+	}
+	f.Set(epoch.Add(time.Hour))
+	if got := f.Now(); !got.Equal(epoch.Add(time.Hour)) {
+		t.Fatalf("Now after Set = %v, want %v", got, epoch.Add(time.Hour))
+	}
+}
+
+func TestFakeTimerFiresAtDeadline(t *testing.T) {
+	f := NewFake(epoch)
+	tm := f.NewTimer(time.Second)
+
+	f.Advance(999 * time.Millisecond)
+	if _, ok := ready(tm.Chan()); ok {
+		t.Fatal("timer fired before its deadline")
+	}
+	// The value sent is the deadline, even though the time moves past it.
+	f.Advance(time.Second)
+	got, ok := ready(tm.Chan())
+	if !ok || !got.Equal(epoch.Add(time.Second)) {
+		t.Fatalf("timer sent %v, %v; want %v, true", got, ok, epoch.Add(time.Second))
+	}
+	if tm.Stop() {
+		t.Fatal("Stop after firing = true, want false")
+	}
+}
+
+func TestFakeTimersFireInOrder(t *testing.T) {
+	f := NewFake(epoch)
+	late := f.NewTimer(2 * time.Second)
+	early := f.NewTimer(time.Second)
+	f.Set(epoch.Add(3 * time.Second))
+
+	e, _ := ready(early.Chan())
+	l, _ := ready(late.Chan())
+	if !e.Equal(epoch.Add(time.Second)) || !l.Equal(epoch.Add(2*time.Second)) {
+		t.Fatalf("timers sent %v and %v, want their own deadlines", e, l)
+	}
+}
+
+func TestFakeSetBackwardsFiresNothing(t *testing.T) {
+	f := NewFake(epoch)
+	tm := f.NewTimer(time.Second)
+	f.Set(epoch.Add(-time.Hour))
+	if _, ok := ready(tm.Chan()); ok {
+		t.Fatal("timer fired when time moved backwards")
+	}
+}
+
+func TestFakeTimerStopDrains(t *testing.T) {
+	f := NewFake(epoch)
+	tm := f.NewTimer(time.Second)
+	if !tm.Stop() {
+		t.Fatal("Stop of a pending timer = false, want true")
+	}
+	f.Advance(time.Minute)
+	if _, ok := ready(tm.Chan()); ok {
+		t.Fatal("stopped timer fired")
+	}
+
+	// A value sent but not yet received is discarded by Stop.
+	tm = f.NewTimer(time.Second)
+	f.Advance(time.Second)
+	tm.Stop()
+	if _, ok := ready(tm.Chan()); ok {
+		t.Fatal("value sent before Stop was received after it")
+	}
+}
+
+func TestFakeTimerResetDrains(t *testing.T) {
+	f := NewFake(epoch)
+	tm := f.NewTimer(time.Second)
+	f.Advance(time.Second)
+
+	if tm.Reset(time.Second) {
+		t.Fatal("Reset of a fired timer = true, want false")
+	}
+	if _, ok := ready(tm.Chan()); ok {
+		t.Fatal("value sent before Reset was received after it")
+	}
+	f.Advance(time.Second)
+	if got, ok := ready(tm.Chan()); !ok || !got.Equal(epoch.Add(2*time.Second)) {
+		t.Fatalf("reset timer sent %v, %v; want %v, true", got, ok, epoch.Add(2*time.Second))
+	}
+
+	if !f.NewTimer(time.Second).Reset(time.Minute) {
+		t.Fatal("Reset of a pending timer = false, want true")
+	}
+}
+
+func TestFakeZeroTimerFiresImmediately(t *testing.T) {
+	f := NewFake(epoch)
+	if _, ok := ready(f.NewTimer(0).Chan()); !ok {
+		t.Fatal("zero timer did not fire")
+	}
+	if _, ok := ready(f.After(-time.Second)); !ok {
+		t.Fatal("negative After did not fire")
+	}
+}
+
+func TestFakeTickerCatchesUp(t *testing.T) {
+	f := NewFake(epoch)
+	tk := f.NewTicker(time.Second)
+	defer tk.Stop()
+
+	f.Advance(time.Second)
+	if got, ok := ready(tk.Chan()); !ok || !got.Equal(epoch.Add(time.Second)) {
+		t.Fatalf("first tick = %v, %v; want %v, true", got, ok, epoch.Add(time.Second))
+	}
+
+	// Moving several periods at once fires the ticker for each of them, but as with a real ticker the
+	// channel holds one value and the ticks nobody received are dropped.
+	f.Advance(3 * time.Second)
+	if got, ok := ready(tk.Chan()); !ok || !got.Equal(epoch.Add(2*time.Second)) {
+		t.Fatalf("tick after catching up = %v, %v; want %v, true", got, ok, epoch.Add(2*time.Second))
+	}
+	if _, ok := ready(tk.Chan()); ok {
+		t.Fatal("dropped ticks were delivered")
+	}
+
+	// The schedule itself didn't drift: the next tick is at 5s.
+	f.Advance(999 * time.Millisecond)
+	if _, ok := ready(tk.Chan()); ok {
+		t.Fatal("ticker fired early")
+	}
+	f.Advance(time.Millisecond)
+	if got, ok := ready(tk.Chan()); !ok || !got.Equal(epoch.Add(5*time.Second)) {
+		t.Fatalf("next tick = %v, %v; want %v, true", got, ok, epoch.Add(5*time.Second))
+	}
+}
+
+func TestFakeTickerStopAndReset(t *testing.T) {
+	f := NewFake(epoch)
+	tk := f.NewTicker(time.Second)
+	f.Advance(time.Second)
+	tk.Stop()
+	if _, ok := ready(tk.Chan()); ok {
+		t.Fatal("tick sent before Stop was received after it")
+	}
+	f.Advance(time.Minute)
+	if _, ok := ready(tk.Chan()); ok {
+		t.Fatal("stopped ticker fired")
+	}
+
+	// Reset restarts the ticker with the new period, counted from now.
+	tk.Reset(2 * time.Second)
+	f.Advance(time.Second)
+	if _, ok := ready(tk.Chan()); ok {
+		t.Fatal("reset ticker fired early")
+	}
+	f.Advance(time.Second)
+	if _, ok := ready(tk.Chan()); !ok {
+		t.Fatal("reset ticker did not fire")
+	}
+	tk.Stop()
+}
+
+func TestFakeSleepAndBlockUntil(t *testing.T) {
+	f := NewFake(epoch)
+	done := make(chan struct{})
+	go func() {
+		f.Sleep(time.Second)
+		close(done)
+	}()
+
+	f.BlockUntil(1)
+	select {
+	case <-done:
+		t.Fatal("Sleep returned before the time moved")
+	default:
+	}
+	f.Advance(time.Second)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Sleep did not return")
+	}
+}
+
+func TestFakeNewTickerPanicsOnNonPositive(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("NewTicker(0) did not panic")
+		}
+	}()
+	NewFake(epoch).NewTicker(0)
+}
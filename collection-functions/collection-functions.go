@@ -1,83 +1,98 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
+
+	"github.com/yanw2/go-by-example/collection-functions/collection"
+	"github.com/yanw2/go-by-example/collection-functions/seq"
 )
 
-// Index returns the first index of the target string t, or -1 if no match is found.
-func Index(vs []string, t string) int {
-	for i, v := range vs {
-		if v == t {
-			return i
-		}
-	}
-	return -1
-}
+// The collection functions live in the `collection` package, which uses type parameters so they work on
+// slices of any element type instead of just `[]string`.
+func main() {
+	var strs = []string{"peach", "apple", "pear", "plum"}
 
-// Include returns true if the target string t is in the slice.
-func Include(vs []string, t string) bool {
-	return Index(vs, t) >= 0
-}
+	fmt.Println(collection.Index(strs, "pear"))
+	fmt.Println(collection.Include(strs, "grape"))
 
-// Any returns true if one of the strings in the slice satisfies the predicate f.
-func Any(vs []string, f func(string) bool) bool {
-	for _, v := range vs {
-		if f(v) {
-			return true
-		}
-	}
-	return false
-}
+	fmt.Println(collection.Any(strs, func(v string) bool {
+		return strings.HasPrefix(v, "p")
+	}))
 
-// All returns true if all of the strings in the slice satisfy the predicate f.
-func All(vs []string, f func(string) bool) bool {
-	for _, v := range vs {
-		if !f(v) {
-			return false
-		}
-	}
-	return true
-}
+	fmt.Println(collection.All(strs, func(v string) bool {
+		return strings.HasPrefix(v, "p")
+	}))
 
-// Filter returns a new slice containing all strings in the slice that satisfy the predicate f.
-func Filter(vs []string, f func(string) bool) []string {
-	vsf := make([]string, 0)
-	for _, v := range vs {
-		if f(v) {
-			vsf = append(vsf, v)
-		}
-	}
-	return vsf
-}
+	fmt.Println(collection.Filter(strs, func(v string) bool {
+		return strings.Contains(v, "e")
+	}))
 
-// Map returns a new slice containing the results of applying the function f to each string in the
-// original slice.
-func Map(vs []string, f func(string) string) []string {
-	vsm := make([]string, len(vs))
-	for i, v := range vs {
-		vsm[i] = f(v)
-	}
-	return vsm
-}
+	fmt.Println(collection.Map(strs, strings.ToUpper))
 
-func main() {
-	var strs = []string{"peach", "apple", "pear", "plum"}
+	// The same functions work on other element types, and `Map` may change the element type.
+	fmt.Println(collection.Map(strs, func(v string) int {
+		return len(v)
+	}))
 
-	fmt.Println(Index(strs, "pear"))
-	fmt.Println(Include(strs, "grape"))
+	fmt.Println(collection.Reduce(strs, 0, func(acc int, v string) int {
+		return acc + len(v)
+	}))
 
-	fmt.Println(Any(strs, func(v string) bool {
-		return strings.HasPrefix(v, "p")
+	fmt.Println(collection.FlatMap(strs[:2], func(v string) []string {
+		return strings.Split(v, "")
 	}))
 
-	fmt.Println(All(strs, func(v string) bool {
-		return strings.HasPrefix(v, "p")
+	fmt.Println(collection.GroupBy(strs, func(v string) string {
+		return v[:1]
 	}))
 
-	fmt.Println(Filter(strs, func(v string) bool {
-		return strings.Contains(v, "e")
+	fmt.Println(collection.Partition(strs, func(v string) bool {
+		return len(v) > 4
 	}))
 
-	fmt.Println(Map(strs, strings.ToUpper))
+	nums := []int{1, 2, 3, 2, 4, 1, 5}
+	fmt.Println(collection.Chunk(nums, 3))
+	fmt.Println(collection.Window(nums, 3))
+	fmt.Println(collection.Distinct(nums))
+	fmt.Println(collection.Zip(strs, nums))
+
+	fmt.Println(collection.Find(nums, func(n int) bool {
+		return n > 3
+	}))
+
+	// `ParallelMap` and `ParallelFilter` spread the work over a bounded pool of goroutines while keeping
+	// the results in input order. The mapper receives a context that is cancelled on the first error.
+	ctx := context.Background()
+	fmt.Println(collection.ParallelMap(ctx, strs, 2, func(_ context.Context, v string) (string, error) {
+		return strings.ToUpper(v), nil
+	}))
+	fmt.Println(collection.ParallelFilter(ctx, nums, 3, func(_ context.Context, n int) (bool, error) {
+		return n%2 == 1, nil
+	}))
+	fmt.Println(collection.ParallelMap(ctx, nums, 3, func(_ context.Context, n int) (int, error) {
+		if n == 4 {
+			return 0, errors.New("can't work with 4")
+		}
+		return n * 2, nil
+	}))
+
+	// The `seq` package offers lazy versions built on range-over-func iterators. Nothing runs until the
+	// pipeline is consumed, and `Take` stops pulling from the earlier stages once it has enough values.
+	evens := seq.Filter(seq.Values(nums), func(n int) bool {
+		return n%2 == 0
+	})
+	squares := seq.Map(evens, func(n int) int {
+		return n * n
+	})
+	fmt.Println(seq.Collect(seq.Take(squares, 2)))
+	fmt.Println(seq.Collect(seq.TakeWhile(seq.Values(nums), func(n int) bool {
+		return n < 4
+	})))
+	fmt.Println(seq.Collect(seq.Skip(seq.Values(strs), 2)))
+
+	// The benchmarks in `seq/seq_test.go` compare the two approaches on a large input. Run them with
+	// `go test -bench . ./collection-functions/seq`.
 }
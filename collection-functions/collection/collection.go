@@ -0,0 +1,177 @@
+// Package collection provides generic versions of the collection functions from the
+// collection-functions example, so they can be used on slices of any element type.
+package collection
+
+// Index returns the first index of the target value t, or -1 if no match is found.
+func Index[T comparable](vs []T, t T) int {
+	for i, v := range vs {
+		if v == t {
+			return i
+		}
+	}
+	return -1
+}
+
+// Include returns true if the target value t is in the slice.
+func Include[T comparable](vs []T, t T) bool {
+	return Index(vs, t) >= 0
+}
+
+// Any returns true if one of the values in the slice satisfies the predicate f.
+func Any[T any](vs []T, f func(T) bool) bool {
+	for _, v := range vs {
+		if f(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// All returns true if all of the values in the slice satisfy the predicate f.
+func All[T any](vs []T, f func(T) bool) bool {
+	for _, v := range vs {
+		if !f(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Find returns the first value in the slice that satisfies the predicate f. The boolean result
+// reports whether such a value was found.
+func Find[T any](vs []T, f func(T) bool) (T, bool) {
+	for _, v := range vs {
+		if f(v) {
+			return v, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// Filter returns a new slice containing all values in the slice that satisfy the predicate f.
+func Filter[T any](vs []T, f func(T) bool) []T {
+	vsf := make([]T, 0)
+	for _, v := range vs {
+		if f(v) {
+			vsf = append(vsf, v)
+		}
+	}
+	return vsf
+}
+
+// Map returns a new slice containing the results of applying the function f to each value in the
+// original slice.
+func Map[T, U any](vs []T, f func(T) U) []U {
+	vsm := make([]U, len(vs))
+	for i, v := range vs {
+		vsm[i] = f(v)
+	}
+	return vsm
+}
+
+// Reduce folds the slice into a single value, starting from init and applying f to the running
+// accumulator and each value in turn.
+func Reduce[T, A any](vs []T, init A, f func(A, T) A) A {
+	acc := init
+	for _, v := range vs {
+		acc = f(acc, v)
+	}
+	return acc
+}
+
+// FlatMap applies f to each value in the slice and concatenates the resulting slices.
+func FlatMap[T, U any](vs []T, f func(T) []U) []U {
+	vsm := make([]U, 0, len(vs))
+	for _, v := range vs {
+		vsm = append(vsm, f(v)...)
+	}
+	return vsm
+}
+
+// GroupBy groups the values in the slice by the key returned from f. Values within each group keep
+// their original order.
+func GroupBy[T any, K comparable](vs []T, f func(T) K) map[K][]T {
+	groups := make(map[K][]T)
+	for _, v := range vs {
+		k := f(v)
+		groups[k] = append(groups[k], v)
+	}
+	return groups
+}
+
+// Partition splits the slice into the values that satisfy the predicate f and the values that do
+// not, preserving order in both.
+func Partition[T any](vs []T, f func(T) bool) (matched, rest []T) {
+	matched = make([]T, 0)
+	rest = make([]T, 0)
+	for _, v := range vs {
+		if f(v) {
+			matched = append(matched, v)
+		} else {
+			rest = append(rest, v)
+		}
+	}
+	return matched, rest
+}
+
+// Chunk splits the slice into consecutive chunks of n values. The last chunk may be shorter than n.
+// The chunks share the backing array of vs. Chunk panics if n is less than 1.
+func Chunk[T any](vs []T, n int) [][]T {
+	if n < 1 {
+		panic("collection: chunk size must be positive")
+	}
+	chunks := make([][]T, 0, (len(vs)+n-1)/n)
+	for i := 0; i < len(vs); i += n {
+		end := min(i+n, len(vs))
+		chunks = append(chunks, vs[i:end:end])
+	}
+	return chunks
+}
+
+// Pair holds two values of possibly different types, as produced by Zip.
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// Zip pairs up the values of as and bs by index. The result is as long as the shorter input.
+func Zip[A, B any](as []A, bs []B) []Pair[A, B] {
+	n := min(len(as), len(bs))
+	ps := make([]Pair[A, B], n)
+	for i := 0; i < n; i++ {
+		ps[i] = Pair[A, B]{as[i], bs[i]}
+	}
+	return ps
+}
+
+// Distinct returns a new slice with duplicate values removed, keeping the first occurrence of each.
+func Distinct[T comparable](vs []T) []T {
+	seen := make(map[T]struct{}, len(vs))
+	vsd := make([]T, 0)
+	for _, v := range vs {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		vsd = append(vsd, v)
+	}
+	return vsd
+}
+
+// Window returns every run of n consecutive values in the slice, sliding forward one value at a time.
+// It returns an empty slice if vs has fewer than n values. The windows share the backing array of vs.
+// Window panics if n is less than 1.
+func Window[T any](vs []T, n int) [][]T {
+	if n < 1 {
+		panic("collection: window size must be positive")
+	}
+	if len(vs) < n {
+		return [][]T{}
+	}
+	windows := make([][]T, 0, len(vs)-n+1)
+	for i := 0; i+n <= len(vs); i++ {
+		windows = append(windows, vs[i:i+n:i+n])
+	}
+	return windows
+}
@@ -0,0 +1,88 @@
+package collection
+
+import (
+	"context"
+	"sync"
+)
+
+// ParallelMap is like Map but applies f to the values using a pool of at most `workers` goroutines,
+// in the style of the worker-pools example. Results keep the order of the input. The first error
+// returned by f cancels the context passed to the remaining calls and is returned once all workers
+// have stopped. If ctx is cancelled before all values are mapped, ctx.Err() is returned.
+func ParallelMap[T, U any](ctx context.Context, vs []T, workers int, f func(context.Context, T) (U, error)) ([]U, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	workers = min(workers, len(vs))
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	vsm := make([]U, len(vs))
+	jobs := make(chan int)
+
+	// Each worker receives the index of a value on `jobs` and writes its result to the same index of
+	// `vsm`, so no further synchronization is needed to preserve order.
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				u, err := f(ctx, vs[i])
+				if err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				vsm[i] = u
+			}
+		}()
+	}
+
+feed:
+	for i := range vs {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := parent.Err(); err != nil {
+		return nil, err
+	}
+	return vsm, nil
+}
+
+// ParallelFilter is like Filter but evaluates the predicate f using a pool of at most `workers`
+// goroutines. The returned values keep the order of the input. Errors and cancellation are handled
+// as in ParallelMap.
+func ParallelFilter[T any](ctx context.Context, vs []T, workers int, f func(context.Context, T) (bool, error)) ([]T, error) {
+	keep, err := ParallelMap(ctx, vs, workers, f)
+	if err != nil {
+		return nil, err
+	}
+	vsf := make([]T, 0)
+	for i, v := range vs {
+		if keep[i] {
+			vsf = append(vsf, v)
+		}
+	}
+	return vsf, nil
+}
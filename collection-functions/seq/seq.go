@@ -0,0 +1,116 @@
+// Package seq provides lazy versions of the collection functions built on range-over-func iterators.
+// Each stage pulls values from the previous one on demand, so chained transformations never allocate
+// intermediate slices and stop as soon as a later stage has seen enough.
+package seq
+
+import "iter"
+
+// Values returns an iterator over the values in the slice, in order.
+func Values[T any](vs []T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, v := range vs {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Filter returns an iterator over the values of s that satisfy the predicate f.
+func Filter[T any](s iter.Seq[T], f func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range s {
+			if f(v) && !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Map returns an iterator over the results of applying the function f to each value of s.
+func Map[T, U any](s iter.Seq[T], f func(T) U) iter.Seq[U] {
+	return func(yield func(U) bool) {
+		for v := range s {
+			if !yield(f(v)) {
+				return
+			}
+		}
+	}
+}
+
+// Take returns an iterator over at most the first n values of s. Once n values have been yielded, s
+// is not advanced any further.
+func Take[T any](s iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if n <= 0 {
+			return
+		}
+		i := 0
+		for v := range s {
+			if !yield(v) {
+				return
+			}
+			i++
+			if i == n {
+				return
+			}
+		}
+	}
+}
+
+// Skip returns an iterator over the values of s after the first n.
+func Skip[T any](s iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		i := 0
+		for v := range s {
+			if i < n {
+				i++
+				continue
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// TakeWhile returns an iterator over the leading values of s that satisfy the predicate f. It stops
+// at the first value that does not.
+func TakeWhile[T any](s iter.Seq[T], f func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range s {
+			if !f(v) || !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Any returns true if one of the values of s satisfies the predicate f. It stops at the first match.
+func Any[T any](s iter.Seq[T], f func(T) bool) bool {
+	for v := range s {
+		if f(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// All returns true if all of the values of s satisfy the predicate f. It stops at the first mismatch.
+func All[T any](s iter.Seq[T], f func(T) bool) bool {
+	for v := range s {
+		if !f(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Collect runs the pipeline and returns its values in a new slice.
+func Collect[T any](s iter.Seq[T]) []T {
+	vs := make([]T, 0)
+	for v := range s {
+		vs = append(vs, v)
+	}
+	return vs
+}
@@ -0,0 +1,157 @@
+package seq
+
+import (
+	"iter"
+	"slices"
+	"testing"
+
+	"github.com/yanw2/go-by-example/collection-functions/collection"
+)
+
+// counting returns an iterator over 0, 1, 2, ... up to n, and a pointer to the number of values it
+// has yielded so far.
+func counting(n int) (iter.Seq[int], *int) {
+	read := new(int)
+	return func(yield func(int) bool) {
+		for i := 0; i < n; i++ {
+			*read++
+			if !yield(i) {
+				return
+			}
+		}
+	}, read
+}
+
+func isEven(n int) bool { return n%2 == 0 }
+
+func square(n int) int { return n * n }
+
+func TestPipeline(t *testing.T) {
+	nums := []int{1, 2, 3, 2, 4, 1, 5}
+	tests := []struct {
+		name string
+		got  []int
+		want []int
+	}{
+		{"Values", Collect(Values(nums)), nums},
+		{"Filter", Collect(Filter(Values(nums), isEven)), []int{2, 2, 4}},
+		{"Map", Collect(Map(Values(nums), square)), []int{1, 4, 9, 4, 16, 1, 25}},
+		{"Take", Collect(Take(Values(nums), 3)), []int{1, 2, 3}},
+		{"Take more than there are", Collect(Take(Values(nums), 10)), nums},
+		{"Take none", Collect(Take(Values(nums), 0)), []int{}},
+		{"Skip", Collect(Skip(Values(nums), 5)), []int{1, 5}},
+		{"TakeWhile", Collect(TakeWhile(Values(nums), func(n int) bool { return n < 4 })), []int{1, 2, 3, 2}},
+		{"chained", Collect(Take(Map(Filter(Values(nums), isEven), square), 2)), []int{4, 4}},
+	}
+	for _, tt := range tests {
+		if !slices.Equal(tt.got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestStagesStopReadingEarly(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(s iter.Seq[int])
+		want int
+	}{
+		{"Take", func(s iter.Seq[int]) { Collect(Take(s, 3)) }, 3},
+		{"Take after Filter", func(s iter.Seq[int]) { Collect(Take(Filter(s, isEven), 3)) }, 5},
+		{"TakeWhile", func(s iter.Seq[int]) { Collect(TakeWhile(s, func(n int) bool { return n < 4 })) }, 5},
+		{"Any", func(s iter.Seq[int]) { Any(s, func(n int) bool { return n == 6 }) }, 7},
+		{"All", func(s iter.Seq[int]) { All(s, func(n int) bool { return n < 2 }) }, 3},
+	}
+	for _, tt := range tests {
+		s, read := counting(1000)
+		tt.run(s)
+		if *read != tt.want {
+			t.Errorf("%s read %d values from the source, want %d", tt.name, *read, tt.want)
+		}
+	}
+}
+
+func TestAnyAndAllReadEverythingWhenTheyMust(t *testing.T) {
+	s, read := counting(10)
+	if Any(s, func(n int) bool { return n > 10 }) {
+		t.Error("Any = true, want false")
+	}
+	if *read != 10 {
+		t.Errorf("Any read %d values, want 10", *read)
+	}
+
+	s, read = counting(10)
+	if !All(s, func(n int) bool { return n < 10 }) {
+		t.Error("All = false, want true")
+	}
+	if *read != 10 {
+		t.Errorf("All read %d values, want 10", *read)
+	}
+}
+
+func TestLazyMatchesEager(t *testing.T) {
+	eager := collection.Map(collection.Filter(large, isEven), square)
+	lazy := Collect(Map(Filter(Values(large), isEven), square))
+	if !slices.Equal(eager, lazy) {
+		t.Fatal("lazy and eager pipelines produced different values")
+	}
+}
+
+// The benchmarks compare the collection functions with the lazy pipeline, in pairs that produce the
+// same output. FilterMap collects every value, so both sides allocate their results; FilterMapSum
+// reduces them instead, which the lazy pipeline can do without allocating at all. First10 shows what
+// stopping early saves on top.
+var large = func() []int {
+	vs := make([]int, 100000)
+	for i := range vs {
+		vs[i] = i
+	}
+	return vs
+}()
+
+var sink int
+
+func BenchmarkEagerFilterMap(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sink += len(collection.Map(collection.Filter(large, isEven), square))
+	}
+}
+
+func BenchmarkLazyFilterMap(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sink += len(Collect(Map(Filter(Values(large), isEven), square)))
+	}
+}
+
+func BenchmarkEagerFilterMapSum(b *testing.B) {
+	b.ReportAllocs()
+	add := func(acc, n int) int { return acc + n }
+	for i := 0; i < b.N; i++ {
+		sink += collection.Reduce(collection.Map(collection.Filter(large, isEven), square), 0, add)
+	}
+}
+
+func BenchmarkLazyFilterMapSum(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for v := range Map(Filter(Values(large), isEven), square) {
+			sink += v
+		}
+	}
+}
+
+func BenchmarkEagerFirst10(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sink += len(collection.Map(collection.Filter(large, isEven), square)[:10])
+	}
+}
+
+func BenchmarkLazyFirst10(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sink += len(Collect(Take(Map(Filter(Values(large), isEven), square), 10)))
+	}
+}
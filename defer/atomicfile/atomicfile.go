@@ -0,0 +1,153 @@
+// Package atomicfile writes files so that readers see either the old contents or the new, never a
+// half-written file, even if the program or the machine crashes part way through.
+//
+// The data goes to a temporary file in the same directory, which is flushed to disk and then renamed
+// over the target; a rename within a directory replaces the target in a single step. Every error on
+// the way, including those from flushing and closing that a bare `defer f.Close()` would drop, is
+// returned to the caller.
+package atomicfile
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrClosed is returned by Write and Commit once the file has been committed or closed.
+var ErrClosed = errors.New("atomicfile: file already closed")
+
+// ErrNotDurable is returned by Commit, wrapping the underlying error, when the new contents are
+// already in place but flushing the directory failed. Readers see the new file, but a crash could
+// still bring back the old one.
+var ErrNotDurable = errors.New("atomicfile: file replaced but not flushed to disk")
+
+// File is a file being written atomically. Nothing appears at its path before Commit. It is
+// not safe for concurrent use.
+type File struct {
+	path string
+	perm os.FileMode
+	tmp  *os.File
+	w    *bufio.Writer
+	done bool
+}
+
+// Create starts writing a file that will replace path once committed. The file gets permissions perm
+// exactly, without the umask being applied, whether or not path already exists.
+func Create(path string, perm os.FileMode) (*File, error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	// The leading dot keeps the temporary file out of most directory listings.
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp*")
+	if err != nil {
+		return nil, err
+	}
+	return &File{path: path, perm: perm, tmp: tmp, w: bufio.NewWriter(tmp)}, nil
+}
+
+// Write writes to the temporary file.
+func (f *File) Write(p []byte) (int, error) {
+	if f.done {
+		return 0, ErrClosed
+	}
+	return f.w.Write(p)
+}
+
+// Commit flushes the data to disk and moves the file into place. Whether it succeeds or not, the File
+// can't be used afterwards. On failure the target is left as it was, except that an error matching
+// ErrNotDurable means the rename has already happened.
+func (f *File) Commit() (err error) {
+	if f.done {
+		return ErrClosed
+	}
+	f.done = true
+
+	// From here on any failure leaves a temporary file that nothing will use, so remove it. The
+	// deferred function sees the error being returned because it is a named result. Once renamed, the
+	// temporary file is the target, so it must stay.
+	renamed := false
+	defer func() {
+		if err != nil && !renamed {
+			f.tmp.Close()
+			os.Remove(f.tmp.Name())
+		}
+	}()
+
+	if err := f.w.Flush(); err != nil {
+		return err
+	}
+	if err := f.tmp.Chmod(f.perm); err != nil {
+		return err
+	}
+	// Sync makes sure the data is on disk before the rename makes it visible. Without it, a crash
+	// soon after the rename can leave an empty file in place of both the old and the new contents.
+	if err := f.tmp.Sync(); err != nil {
+		return err
+	}
+	if err := f.tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.tmp.Name(), f.path); err != nil {
+		return err
+	}
+	renamed = true
+	if err := syncDir(filepath.Dir(f.path)); err != nil {
+		return fmt.Errorf("%w: %w", ErrNotDurable, err)
+	}
+	return nil
+}
+
+// syncDir flushes a directory, so that a rename within it survives a crash.
+func syncDir(dir string) (err error) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	// Report the error from Close unless there is already one to report.
+	defer func() {
+		if cerr := d.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	return d.Sync()
+}
+
+// Close abandons the file if it hasn't been committed, removing the temporary file. After Commit it
+// does nothing, so `defer f.Close()` straight after Create cleans up on every path that doesn't reach
+// Commit. It is safe to call Close more than once.
+func (f *File) Close() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+	err := f.tmp.Close()
+	if rerr := os.Remove(f.tmp.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// WriteFile atomically replaces path with what write writes, with permissions perm as for Create. If
+// write returns an error, path is left as it was and the error is returned.
+func WriteFile(path string, perm os.FileMode, write func(w io.Writer) error) (err error) {
+	f, err := Create(path, perm)
+	if err != nil {
+		return err
+	}
+	// Close only fails if Commit was never reached, in which case there is already an error to
+	// return, so keep that one.
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	if err := write(f); err != nil {
+		return err
+	}
+	return f.Commit()
+}
@@ -1,32 +1,46 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+
+	"github.com/yanw2/go-by-example/defer/atomicfile"
 )
 
-func createFile(p string) *os.File {
+func createFile(p string) (*os.File, error) {
 	fmt.Println("creating")
-	f, err := os.Create(p)
-	if err != nil {
-		panic(err)
-	}
-	return f
+	return os.Create(p)
 }
 
-func writeFile(f *os.File) {
+// `Fprintln` returns an error too, for instance when the disk is full, so we pass it on.
+func writeFile(f *os.File) error {
 	fmt.Println("writing")
-	fmt.Fprintln(f, "data")
+	_, err := fmt.Fprintln(f, "data")
+	return err
 }
 
-func closeFile(f *os.File) {
+func closeFile(f *os.File) error {
 	fmt.Println("closing")
-	err := f.Close()
-	// It's important to check for errors when closing a file, even in a deferred function.
+	return f.Close()
+}
+
+// It's important to check for errors when closing a file, even in a deferred function: a failed
+// write may only be reported by `Close`. A deferred call's return value is discarded, but a deferred
+// function can still report an error by assigning to a named result of the enclosing function. Here
+// the error from `closeFile` is kept unless `save` is already returning an error of its own.
+func save(p string) (err error) {
+	f, err := createFile(p)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		return err
 	}
+	defer func() {
+		if cerr := closeFile(f); err == nil {
+			err = cerr
+		}
+	}()
+	return writeFile(f)
 }
 
 func main() {
@@ -35,9 +49,30 @@ func main() {
 	// language.
 
 	// Suppose we wanted to create a file, write to it, and then close when we're done. Immediately after
-	// getting a file object with `createFile`, we defer the closing of that file with `closeFile`. This
-	// will be executed at the end of the enclosing function (main), after `writeFile` has finished.
-	f := createFile("/tmp/defer.txt")
-	defer closeFile(f)
-	writeFile(f)
+	// getting a file object with `createFile`, `save` defers the closing of that file with `closeFile`.
+	// This will be executed at the end of the enclosing function (save), after `writeFile` has finished.
+	// Errors from any of the three steps are returned to us rather than crashing the program.
+	if err := save("/tmp/defer.txt"); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	}
+
+	// If the program crashes part way through `save`, the file is left half-written. The
+	// `atomicfile` package writes to a temporary file, flushes it to disk and only then renames it
+	// into place, so readers see either the old contents or the new ones.
+	err := atomicfile.WriteFile("/tmp/defer.txt", 0o644, func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, "new data")
+		return err
+	})
+	fmt.Println("atomic write:", err)
+	data, _ := os.ReadFile("/tmp/defer.txt")
+	fmt.Print("contents: ", string(data))
+
+	// When the write fails, the file keeps its old contents.
+	err = atomicfile.WriteFile("/tmp/defer.txt", 0o644, func(w io.Writer) error {
+		fmt.Fprintln(w, "partial")
+		return errors.New("ran out of data")
+	})
+	fmt.Println("failed write:", err)
+	data, _ = os.ReadFile("/tmp/defer.txt")
+	fmt.Print("contents: ", string(data))
 }
@@ -0,0 +1,378 @@
+// Package breaker provides a circuit breaker, which stops calls to a dependency that keeps failing so
+// that callers fail fast instead of piling more load onto it.
+//
+// A breaker starts closed, letting calls through and recording their outcomes over a rolling window.
+// When the share of failures in the window reaches a threshold it opens, and calls fail straight away
+// with an *OpenError. After a cool-down it is half-open: a few trial calls are let through, and if
+// they all succeed it closes again, while any failure opens it for another cool-down.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+	"github.com/yanw2/go-by-example/errors/errs"
+)
+
+// State is the state of a breaker.
+type State int
+
+// The states of a breaker. An open breaker turns half-open once its cool-down is over, the next time
+// it is used.
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// ErrOpen matches, through errors.Is, every error a breaker returns instead of making a call.
+var ErrOpen = errors.New("breaker: circuit open")
+
+// OpenError is returned by Do instead of making the call, while the breaker is open or while it is
+// half-open with all its trial calls already in flight. It matches ErrOpen and errs.Unavailable
+// through errors.Is, and has the code errs.Unavailable for errs.CodeOf.
+type OpenError struct {
+	// Name is the breaker's name, if it was given one with WithName.
+	Name string
+	// RetryAfter is how long until the breaker lets trial calls through. It is zero when the breaker
+	// is half-open.
+	RetryAfter time.Duration
+}
+
+func (e *OpenError) Error() string {
+	msg := "breaker: circuit open"
+	if e.Name != "" {
+		msg = "breaker " + e.Name + ": circuit open"
+	}
+	if e.RetryAfter > 0 {
+		msg += fmt.Sprintf(", retry after %v", e.RetryAfter)
+	}
+	return msg
+}
+
+// Is reports whether target is ErrOpen or errs.Unavailable.
+func (e *OpenError) Is(target error) bool {
+	return target == ErrOpen || target == errs.Unavailable
+}
+
+// ErrorCode returns errs.Unavailable, implementing errs.Coder.
+func (e *OpenError) ErrorCode() errs.Code {
+	return errs.Unavailable
+}
+
+// Option configures a Breaker.
+type Option func(*Breaker)
+
+// WithName names the breaker, for its errors.
+func WithName(name string) Option {
+	return func(b *Breaker) {
+		b.name = name
+	}
+}
+
+// WithFailureRatio sets the share of failed calls in the window, between 0 and 1, at which the
+// breaker opens. The default is 0.5. New panics if r is not positive, since a breaker that opens at
+// a ratio of 0 would open on successes too.
+func WithFailureRatio(r float64) Option {
+	return func(b *Breaker) {
+		b.ratio = r
+	}
+}
+
+// WithMinCalls sets how many calls the window must hold before the breaker can open, so that a
+// single early failure doesn't open it. The default is 10.
+func WithMinCalls(n int) Option {
+	return func(b *Breaker) {
+		b.minCalls = n
+	}
+}
+
+// WithWindow sets how far back the breaker looks when working out the failure ratio. The default is
+// 10 seconds. The window is split into 10 buckets, so it is raised to at least 10ns.
+func WithWindow(d time.Duration) Option {
+	return func(b *Breaker) {
+		b.window = d
+	}
+}
+
+// WithCoolDown sets how long the breaker stays open before letting trial calls through. The default
+// is 30 seconds.
+func WithCoolDown(d time.Duration) Option {
+	return func(b *Breaker) {
+		b.coolDown = d
+	}
+}
+
+// WithTrialCalls sets how many trial calls the half-open breaker lets through, all of which must
+// succeed for it to close. The default is 1. New panics if n is less than 1, since the breaker
+// could then never close again.
+func WithTrialCalls(n int) Option {
+	return func(b *Breaker) {
+		b.trials = n
+	}
+}
+
+// WithIsFailure sets which errors count as failures. The default counts every error except those
+// matching context.Canceled, since a caller giving up says nothing about the dependency. Errors that
+// aren't failures, such as errs.Invalid from a bad request, still reach the caller, but count neither
+// for nor against the dependency: they don't go into the window, and a half-open trial call that ends
+// in one just frees its slot for another trial.
+func WithIsFailure(f func(error) bool) Option {
+	return func(b *Breaker) {
+		b.isFailure = f
+	}
+}
+
+// WithOnStateChange sets a function called on every change of state. It is called synchronously, by
+// the goroutine whose call caused the change, without the breaker's lock held.
+func WithOnStateChange(f func(from, to State)) Option {
+	return func(b *Breaker) {
+		b.onChange = f
+	}
+}
+
+// WithClock sets the clock that the failure window and the cool-down are measured on. The default is
+// the system clock.
+func WithClock(clk clock.Clock) Option {
+	return func(b *Breaker) {
+		b.clock = clk
+	}
+}
+
+// buckets is the number of parts the window is divided into. Outcomes expire a bucket at a time, so
+// the window effectively slides in steps of window/buckets.
+const buckets = 10
+
+type bucket struct {
+	epoch            int64
+	successes, fails int
+}
+
+// Breaker is a circuit breaker. It is safe for concurrent use.
+type Breaker struct {
+	name      string
+	ratio     float64
+	minCalls  int
+	window    time.Duration
+	coolDown  time.Duration
+	trials    int
+	isFailure func(error) bool
+	onChange  func(from, to State)
+	clock     clock.Clock
+
+	mu    sync.Mutex
+	state State
+	// generation changes with every change of state, so that the outcome of a call that started
+	// before the change isn't counted after it.
+	generation uint64
+	buckets    [buckets]bucket
+	openedAt   time.Time
+	inFlight   int
+	succeeded  int
+}
+
+// New returns a closed breaker.
+func New(opts ...Option) *Breaker {
+	b := &Breaker{
+		ratio:    0.5,
+		minCalls: 10,
+		window:   10 * time.Second,
+		coolDown: 30 * time.Second,
+		trials:   1,
+		isFailure: func(err error) bool {
+			return !errors.Is(err, context.Canceled)
+		},
+		clock: clock.Real(),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.ratio <= 0 {
+		panic("breaker: non-positive failure ratio")
+	}
+	if b.trials < 1 {
+		panic("breaker: fewer than one trial call")
+	}
+	b.window = max(b.window, buckets)
+	return b
+}
+
+// outcome is how a call counts towards the breaker's state.
+type outcome int
+
+const (
+	success outcome = iota
+	failure
+	neutral
+)
+
+// Do calls fn if the breaker allows it and records the outcome, returning fn's error. Otherwise it
+// returns an *OpenError without calling fn. If fn panics, the call counts as a failure and the panic
+// carries on up the stack.
+func (b *Breaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	gen, err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	// Without this, a panicking trial call would hold its half-open slot forever.
+	panicking := true
+	defer func() {
+		if panicking {
+			b.record(gen, failure)
+		}
+	}()
+	err = fn(ctx)
+	panicking = false
+
+	switch {
+	case err == nil:
+		b.record(gen, success)
+	case b.isFailure(err):
+		b.record(gen, failure)
+	default:
+		b.record(gen, neutral)
+	}
+	return err
+}
+
+// State returns the breaker's current state.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	from := b.state
+	b.checkCoolDownLocked()
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	return to
+}
+
+// checkCoolDownLocked moves an open breaker to half-open once its cool-down is over.
+func (b *Breaker) checkCoolDownLocked() {
+	if b.state == Open && b.clock.Now().Sub(b.openedAt) >= b.coolDown {
+		b.setStateLocked(HalfOpen)
+	}
+}
+
+func (b *Breaker) allow() (uint64, error) {
+	b.mu.Lock()
+	from := b.state
+	b.checkCoolDownLocked()
+	to := b.state
+
+	var err error
+	switch b.state {
+	case Open:
+		err = &OpenError{Name: b.name, RetryAfter: b.openedAt.Add(b.coolDown).Sub(b.clock.Now())}
+	case HalfOpen:
+		if b.inFlight >= b.trials-b.succeeded {
+			err = &OpenError{Name: b.name}
+		} else {
+			b.inFlight++
+		}
+	}
+	gen := b.generation
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	return gen, err
+}
+
+func (b *Breaker) record(gen uint64, o outcome) {
+	b.mu.Lock()
+	from := b.state
+	if gen == b.generation {
+		switch b.state {
+		case Closed:
+			if o == neutral {
+				break
+			}
+			bk := b.bucketLocked()
+			if o == failure {
+				bk.fails++
+			} else {
+				bk.successes++
+			}
+			if successes, fails := b.countsLocked(); successes+fails >= b.minCalls &&
+				float64(fails)/float64(successes+fails) >= b.ratio {
+				b.setStateLocked(Open)
+			}
+		case HalfOpen:
+			b.inFlight--
+			switch o {
+			case failure:
+				b.setStateLocked(Open)
+			case success:
+				if b.succeeded++; b.succeeded >= b.trials {
+					b.setStateLocked(Closed)
+				}
+			}
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+// bucketLocked returns the bucket for the current time, clearing it first if it last held outcomes
+// from a previous pass of the window.
+func (b *Breaker) bucketLocked() *bucket {
+	epoch := b.epochLocked()
+	bk := &b.buckets[epoch%buckets]
+	if bk.epoch != epoch {
+		*bk = bucket{epoch: epoch}
+	}
+	return bk
+}
+
+func (b *Breaker) epochLocked() int64 {
+	return b.clock.Now().UnixNano() / int64(b.window/buckets)
+}
+
+// countsLocked adds up the outcomes of the buckets still in the window.
+func (b *Breaker) countsLocked() (successes, fails int) {
+	epoch := b.epochLocked()
+	for _, bk := range b.buckets {
+		if bk.epoch > epoch-buckets {
+			successes += bk.successes
+			fails += bk.fails
+		}
+	}
+	return successes, fails
+}
+
+func (b *Breaker) setStateLocked(s State) {
+	b.state = s
+	b.generation++
+	b.inFlight, b.succeeded = 0, 0
+	switch s {
+	case Open:
+		b.openedAt = b.clock.Now()
+	case Closed:
+		b.buckets = [buckets]bucket{}
+	}
+}
+
+func (b *Breaker) notify(from, to State) {
+	if from != to && b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
@@ -0,0 +1,271 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+	"github.com/yanw2/go-by-example/errors/errs"
+)
+
+var errDown = errors.New("down")
+
+func fail(ctx context.Context) error { return errDown }
+
+func succeed(ctx context.Context) error { return nil }
+
+func cancelled(ctx context.Context) error { return context.Canceled }
+
+// openBreaker returns a breaker with a 1s cool-down that a single failure opens, and the fake clock
+// it runs on.
+func openBreaker(t *testing.T, opts ...Option) (*Breaker, *clock.Fake) {
+	t.Helper()
+	fake := clock.NewFake(time.Now())
+	opts = append([]Option{WithClock(fake), WithMinCalls(1), WithCoolDown(time.Second)}, opts...)
+	b := New(opts...)
+	b.Do(context.Background(), fail)
+	if got := b.State(); got != Open {
+		t.Fatalf("state after failure = %v, want %v", got, Open)
+	}
+	return b, fake
+}
+
+// assertState fails the test unless the breaker is in state want.
+func assertState(t *testing.T, b *Breaker, want State) {
+	t.Helper()
+	if got := b.State(); got != want {
+		t.Fatalf("state = %v, want %v", got, want)
+	}
+}
+
+func TestOpensAtFailureRatioOnceThereAreEnoughCalls(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	b := New(WithClock(fake), WithMinCalls(4), WithFailureRatio(0.5), WithCoolDown(time.Minute), WithName("db"))
+
+	// Two failures out of three is over the ratio, but the window doesn't hold enough calls yet.
+	b.Do(context.Background(), fail)
+	b.Do(context.Background(), succeed)
+	b.Do(context.Background(), fail)
+	assertState(t, b, Closed)
+
+	b.Do(context.Background(), succeed)
+	assertState(t, b, Open)
+
+	called := false
+	err := b.Do(context.Background(), func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	if called {
+		t.Fatal("Do called fn while the breaker was open")
+	}
+	var oe *OpenError
+	if !errors.As(err, &oe) || oe.Name != "db" || oe.RetryAfter != time.Minute {
+		t.Fatalf("Do = %v, want an *OpenError for db with RetryAfter 1m", err)
+	}
+	if !errors.Is(err, ErrOpen) || !errors.Is(err, errs.Unavailable) || errs.CodeOf(err) != errs.Unavailable {
+		t.Fatalf("Do = %v, want it to match ErrOpen and errs.Unavailable", err)
+	}
+}
+
+func TestOutcomesLeaveTheWindow(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	b := New(WithClock(fake), WithMinCalls(2), WithWindow(10*time.Second))
+
+	b.Do(context.Background(), fail)
+	fake.Advance(10 * time.Second)
+	b.Do(context.Background(), fail)
+	assertState(t, b, Closed)
+
+	// The second failure is still in the window, so one more makes two.
+	fake.Advance(5 * time.Second)
+	b.Do(context.Background(), fail)
+	assertState(t, b, Open)
+}
+
+func TestCoolDownMovesToHalfOpen(t *testing.T) {
+	b, fake := openBreaker(t)
+
+	fake.Advance(time.Second - time.Millisecond)
+	var oe *OpenError
+	if err := b.Do(context.Background(), succeed); !errors.As(err, &oe) || oe.RetryAfter != time.Millisecond {
+		t.Fatalf("Do before the cool-down = %v, want an *OpenError with RetryAfter 1ms", err)
+	}
+	assertState(t, b, Open)
+
+	fake.Advance(time.Millisecond)
+	assertState(t, b, HalfOpen)
+}
+
+func TestTrialCalls(t *testing.T) {
+	b, fake := openBreaker(t, WithTrialCalls(2))
+	fake.Advance(time.Second)
+
+	// Hold both trial calls in flight, so that a third call is turned away.
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			done <- b.Do(context.Background(), func(ctx context.Context) error {
+				started <- struct{}{}
+				<-release
+				return nil
+			})
+		}()
+		<-started
+	}
+	var oe *OpenError
+	if err := b.Do(context.Background(), succeed); !errors.As(err, &oe) || oe.RetryAfter != 0 {
+		t.Fatalf("Do with all trials in flight = %v, want an *OpenError with RetryAfter 0", err)
+	}
+
+	// One success isn't enough to close the breaker.
+	release <- struct{}{}
+	if err := <-done; err != nil {
+		t.Fatalf("first trial: Do = %v, want nil", err)
+	}
+	assertState(t, b, HalfOpen)
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("second trial: Do = %v, want nil", err)
+	}
+	assertState(t, b, Closed)
+}
+
+func TestFailedTrialCallReopens(t *testing.T) {
+	b, fake := openBreaker(t, WithTrialCalls(2))
+	fake.Advance(time.Second)
+
+	b.Do(context.Background(), succeed)
+	b.Do(context.Background(), fail)
+	assertState(t, b, Open)
+
+	// The cool-down starts again from the failed trial.
+	fake.Advance(time.Second - time.Millisecond)
+	assertState(t, b, Open)
+	fake.Advance(time.Millisecond)
+	assertState(t, b, HalfOpen)
+}
+
+func TestOnStateChange(t *testing.T) {
+	var changes []string
+	b, fake := openBreaker(t, WithOnStateChange(func(from, to State) {
+		changes = append(changes, from.String()+" -> "+to.String())
+	}))
+	fake.Advance(time.Second)
+	b.Do(context.Background(), succeed)
+
+	want := []string{"closed -> open", "open -> half-open", "half-open -> closed"}
+	if !slices.Equal(changes, want) {
+		t.Fatalf("state changes = %q, want %q", changes, want)
+	}
+}
+
+func TestNewValidatesOptions(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opt  Option
+	}{
+		{"zero ratio", WithFailureRatio(0)},
+		{"no trial calls", WithTrialCalls(0)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("New didn't panic")
+				}
+			}()
+			New(tt.opt)
+		})
+	}
+}
+
+func TestTinyWindowIsRaised(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	b := New(WithClock(fake), WithWindow(0), WithMinCalls(2))
+
+	b.Do(context.Background(), succeed)
+	b.Do(context.Background(), succeed)
+	assertState(t, b, Closed)
+}
+
+func TestCancelledTrialCallDoesNotCloseBreaker(t *testing.T) {
+	b, fake := openBreaker(t)
+	fake.Advance(time.Second)
+
+	if err := b.Do(context.Background(), cancelled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Do = %v, want context.Canceled", err)
+	}
+	if got := b.State(); got != HalfOpen {
+		t.Fatalf("state after cancelled trial = %v, want %v", got, HalfOpen)
+	}
+
+	// The cancelled call freed its slot, so another trial is let through.
+	if err := b.Do(context.Background(), succeed); err != nil {
+		t.Fatalf("second trial: Do = %v, want nil", err)
+	}
+	if got := b.State(); got != Closed {
+		t.Fatalf("state after successful trial = %v, want %v", got, Closed)
+	}
+}
+
+func TestNonFailuresDoNotDiluteFailureRatio(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	b := New(WithClock(fake), WithMinCalls(2), WithFailureRatio(0.5))
+
+	for i := 0; i < 10; i++ {
+		b.Do(context.Background(), cancelled)
+	}
+	if got := b.State(); got != Closed {
+		t.Fatalf("state after cancellations = %v, want %v", got, Closed)
+	}
+
+	b.Do(context.Background(), succeed)
+	b.Do(context.Background(), fail)
+	if got := b.State(); got != Open {
+		t.Fatalf("state after 1 success and 1 failure = %v, want %v", got, Open)
+	}
+}
+
+func TestPanickingCallCountsAsFailure(t *testing.T) {
+	b, fake := openBreaker(t)
+	fake.Advance(time.Second)
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Fatalf("recovered %v, want the panic to carry on up the stack", r)
+			}
+		}()
+		b.Do(context.Background(), func(ctx context.Context) error { panic("boom") })
+	}()
+
+	if got := b.State(); got != Open {
+		t.Fatalf("state after panicking trial = %v, want %v", got, Open)
+	}
+	fake.Advance(time.Second)
+	if err := b.Do(context.Background(), succeed); err != nil {
+		t.Fatalf("trial after cool-down: Do = %v, want nil", err)
+	}
+	if got := b.State(); got != Closed {
+		t.Fatalf("state after successful trial = %v, want %v", got, Closed)
+	}
+}
+
+func TestPanickingCallInClosedStateCountsAsFailure(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	b := New(WithClock(fake), WithMinCalls(1))
+
+	func() {
+		defer func() { recover() }()
+		b.Do(context.Background(), func(ctx context.Context) error { panic("boom") })
+	}()
+	if got := b.State(); got != Open {
+		t.Fatalf("state after panicking call = %v, want %v", got, Open)
+	}
+}
@@ -1,8 +1,16 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+	"github.com/yanw2/go-by-example/errors/breaker"
+	"github.com/yanw2/go-by-example/errors/errs"
+	"github.com/yanw2/go-by-example/errors/retry"
 )
 
 // By convention, errors are the last return value and have the type `error`, a built-in interface
@@ -61,4 +69,130 @@ func main() {
 		fmt.Println(ae.arg)
 		fmt.Println(ae.prob)
 	}
+
+	// The type assertion only looks at the outermost error, so it fails as soon as the error is
+	// wrapped with more context. `errors.As` follows the chain of wrapped errors instead.
+	wrapped := fmt.Errorf("loading config: %w", e)
+	if _, ok := wrapped.(*argError); !ok {
+		fmt.Println("type assertion failed on:", wrapped)
+	}
+	var ae *argError
+	if errors.As(wrapped, &ae) {
+		fmt.Println("errors.As found:", ae.arg, ae.prob)
+	}
+
+	structured()
+	retries()
+	breakers()
+}
+
+// The `errs` package goes further: its errors carry a code for programs to act on and key/value
+// fields for context, and they can wrap a cause of any type.
+func f3(arg int) (int, error) {
+	if arg == 42 {
+		return -1, errs.New(errs.Invalid, "can't work with it", "arg", arg)
+	}
+	return arg + 3, nil
+}
+
+func structured() {
+	_, e := f3(42)
+	err := errs.Wrap(e, errs.Unknown, "loading config", "file", "app.conf")
+
+	// Codes are errors themselves, so `errors.Is` finds them anywhere in the chain, and `errors.As`
+	// still gets at the structured error underneath.
+	fmt.Println(err)
+	fmt.Println("invalid:", errors.Is(err, errs.Invalid), "code:", errs.CodeOf(err))
+	fmt.Println("fields:", errs.FieldsOf(err))
+	var se *errs.Error
+	if errors.As(err, &se) {
+		fmt.Println("outer message:", se.Message)
+	}
+
+	// Stack traces are opt-in, since they cost far more to capture than the error itself. `%+v`
+	// prints an error with its codes, fields and stack, and the JSON form is ready for structured
+	// logs.
+	err = errs.WithStack(err)
+	fmt.Printf("%+v\n", err)
+	b, _ := json.Marshal(err)
+	fmt.Println(string(b))
+}
+
+// Some failures are worth another try, such as a timeout talking to a busy server, and some are not,
+// such as a bad argument. The `retry` package calls a function until it succeeds, waiting between
+// attempts according to a backoff policy, and gives up straight away on errors it is told are
+// permanent.
+func retries() {
+	ctx := context.Background()
+
+	// The waits go through a `clock.Clock`, so with a `clock.Fake` the example runs instantly: we wait
+	// for `Do` to start each wait and then move the fake time past it.
+	fake := clock.NewFake(time.Now())
+	calls := 0
+	flaky := func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errs.New(errs.Unavailable, "server busy")
+		}
+		return nil
+	}
+	done := make(chan error)
+	go func() {
+		done <- retry.Do(ctx, flaky,
+			retry.WithClock(fake),
+			retry.WithMaxAttempts(5),
+			retry.WithBackoff(retry.Exponential(time.Second, time.Minute)),
+			retry.WithOnRetry(func(n int, err error, delay time.Duration) {
+				fmt.Printf("retry %d in %v after: %v\n", n, delay, err)
+			}))
+	}()
+	for i := 0; i < 2; i++ {
+		fake.BlockUntil(1)
+		fake.Advance(time.Minute)
+	}
+	fmt.Println("flaky:", <-done, "after", calls, "calls")
+
+	// `f2` fails with an `*argError`, which `errors.As` finds however deeply it is wrapped. Trying
+	// again with the same argument can't help, so we classify it as permanent.
+	_, err := retry.DoValue(ctx, func(ctx context.Context) (int, error) {
+		r, err := f2(42)
+		return r, errs.Wrap(err, errs.Unknown, "calling f2")
+	}, retry.PermanentAs[*argError]())
+	fmt.Println("f2:", err)
+}
+
+// Retrying helps with brief failures, but when a dependency is down for longer, callers that keep
+// calling it only add to its load. A circuit breaker from the `breaker` package watches the outcomes
+// of calls, and once too many fail it opens and fails calls straight away for a cool-down period.
+func breakers() {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Now())
+	b := breaker.New(
+		breaker.WithName("f2"),
+		breaker.WithClock(fake),
+		breaker.WithMinCalls(3),
+		breaker.WithCoolDown(10*time.Second),
+		breaker.WithOnStateChange(func(from, to breaker.State) {
+			fmt.Println("breaker:", from, "->", to)
+		}))
+
+	call := func(arg int) error {
+		return b.Do(ctx, func(ctx context.Context) error {
+			_, err := f2(arg)
+			return err
+		})
+	}
+
+	// Three failures in a row open the breaker, and the next call doesn't reach `f2` at all. The error
+	// it gets matches `breaker.ErrOpen`, and carries the `errs.Unavailable` code.
+	for i := 0; i < 3; i++ {
+		call(42)
+	}
+	err := call(7)
+	fmt.Println(err)
+	fmt.Println("open:", errors.Is(err, breaker.ErrOpen), "code:", errs.CodeOf(err))
+
+	// Once the cool-down is over a trial call goes through, and when it succeeds the breaker closes.
+	fake.Advance(10 * time.Second)
+	fmt.Println("trial:", call(7), "state:", b.State())
 }
@@ -0,0 +1,126 @@
+package errs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Failure records an error together with the input that caused it.
+type Failure[T any] struct {
+	Input T
+	Err   error
+}
+
+func (f *Failure[T]) Error() string {
+	return fmt.Sprintf("%v: %v", f.Input, f.Err)
+}
+
+// Unwrap returns the recorded error, for errors.Is and errors.As.
+func (f *Failure[T]) Unwrap() error {
+	return f.Err
+}
+
+// Collector gathers the errors from work fanned out over goroutines, each with the input that
+// failed. It is safe for concurrent use.
+//
+// A collector may be given a limit. Once that many errors are recorded it is full: Add drops further
+// errors, counting them but not keeping them, and the channel returned by Full is closed so that the
+// remaining work can be abandoned.
+type Collector[T any] struct {
+	limit int
+
+	mu       sync.Mutex
+	failures []*Failure[T]
+	dropped  int
+	full     chan struct{}
+}
+
+// NewCollector returns a collector that keeps at most limit errors. A limit of zero or less means no
+// limit.
+func NewCollector[T any](limit int) *Collector[T] {
+	return &Collector[T]{limit: limit, full: make(chan struct{})}
+}
+
+// Add records err as the result of input, and does nothing if err is nil. It reports whether the
+// collector still has room, so a worker can stop once it returns false.
+func (c *Collector[T]) Add(input T, err error) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		if c.isFull() {
+			c.dropped++
+		} else {
+			c.failures = append(c.failures, &Failure[T]{input, err})
+			if c.isFull() {
+				close(c.full)
+			}
+		}
+	}
+	return !c.isFull()
+}
+
+func (c *Collector[T]) isFull() bool {
+	return c.limit > 0 && len(c.failures) >= c.limit
+}
+
+// Full returns a channel that is closed once the collector reaches its limit. Without a limit, the
+// channel is never closed.
+func (c *Collector[T]) Full() <-chan struct{} {
+	return c.full
+}
+
+// Failures returns the recorded failures in the order they were added.
+func (c *Collector[T]) Failures() []*Failure[T] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*Failure[T](nil), c.failures...)
+}
+
+// Len returns the number of errors added, including any dropped because of the limit.
+func (c *Collector[T]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.failures) + c.dropped
+}
+
+// Err returns nil if no errors were added. Otherwise it returns an error that behaves like one from
+// errors.Join of the recorded failures: its message has one line per failure, and errors.Is and
+// errors.As look at each failure in turn. A final line says how many errors were dropped, if any.
+func (c *Collector[T]) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.failures) == 0 {
+		return nil
+	}
+	errs := make([]error, len(c.failures))
+	for i, f := range c.failures {
+		errs[i] = f
+	}
+	return &joinError{errs: errs, dropped: c.dropped}
+}
+
+type joinError struct {
+	errs    []error
+	dropped int
+}
+
+func (e *joinError) Error() string {
+	var b strings.Builder
+	for i, err := range e.errs {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(err.Error())
+	}
+	if e.dropped > 0 {
+		b.WriteString("\n(and " + strconv.Itoa(e.dropped) + " more)")
+	}
+	return b.String()
+}
+
+// Unwrap returns the joined errors, as for errors.Join.
+func (e *joinError) Unwrap() []error {
+	return e.errs
+}
@@ -0,0 +1,244 @@
+// Package errs provides a structured error type for the errors example. Its errors carry a
+// machine-readable code, key/value fields for context and, optionally, the stack where they were
+// created. They wrap an underlying cause, so they work with errors.Is and errors.As through any
+// number of layers of wrapping, which a plain type assertion such as e.(*argError) does not.
+package errs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+)
+
+// Code classifies an error for programs rather than people, for instance to pick an HTTP status or
+// decide whether to retry. A Code is itself an error, so errors.Is(err, errs.NotFound) reports
+// whether any error in err's chain has that code.
+type Code string
+
+func (c Code) Error() string {
+	return string(c)
+}
+
+// Some common codes. Packages can define their own.
+const (
+	Unknown      Code = ""
+	Invalid      Code = "invalid"
+	NotFound     Code = "not_found"
+	Conflict     Code = "conflict"
+	Unauthorized Code = "unauthorized"
+	Unavailable  Code = "unavailable"
+	Timeout      Code = "timeout"
+	Internal     Code = "internal"
+)
+
+// Coder is implemented by errors that carry a code. Besides *Error, other packages' error types can
+// implement it to take part in CodeOf.
+type Coder interface {
+	ErrorCode() Code
+}
+
+// Field is a piece of context attached to an error.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Error is a structured error. Create one with New or Wrap.
+type Error struct {
+	Code    Code
+	Message string
+	Fields  []Field
+	// Err is the underlying cause, if any.
+	Err   error
+	stack []uintptr
+}
+
+// New returns an error with the given code and message. kv holds alternating keys and values, as with
+// log/slog: New(errs.Invalid, "bad port", "port", p). A key that isn't a string, or has no value, is
+// recorded under "!BADKEY".
+func New(code Code, msg string, kv ...any) error {
+	return &Error{Code: code, Message: msg, Fields: fields(kv)}
+}
+
+// Wrap returns an error that adds a code, message and fields to err. It returns nil if err is nil, so
+// that `return errs.Wrap(err, ...)` is safe whether or not err is set. A code of Unknown leaves err's
+// own code, if it has one, to show through.
+func Wrap(err error, code Code, msg string, kv ...any) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Message: msg, Fields: fields(kv), Err: err}
+}
+
+func fields(kv []any) []Field {
+	if len(kv) == 0 {
+		return nil
+	}
+	fs := make([]Field, 0, (len(kv)+1)/2)
+	for len(kv) > 0 {
+		key, ok := kv[0].(string)
+		if !ok || len(kv) == 1 {
+			fs = append(fs, Field{"!BADKEY", kv[0]})
+			kv = kv[1:]
+			continue
+		}
+		fs = append(fs, Field{key, kv[1]})
+		kv = kv[2:]
+	}
+	return fs
+}
+
+// WithStack returns err with the stack of the calling goroutine attached. If err is an *Error, the
+// result is a copy of it with the stack; otherwise err is wrapped in a new *Error. Stacks are opt-in
+// because capturing one costs far more than creating the error. WithStack returns nil if err is nil.
+func WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	var pcs [32]uintptr
+	// Skip runtime.Callers and WithStack itself.
+	n := runtime.Callers(2, pcs[:])
+
+	e, ok := err.(*Error)
+	if ok {
+		c := *e
+		e = &c
+	} else {
+		e = &Error{Err: err}
+	}
+	e.stack = pcs[:n:n]
+	return e
+}
+
+// Error returns the message followed by the cause's, separated by ": ".
+func (e *Error) Error() string {
+	switch {
+	case e.Err == nil:
+		return e.Message
+	case e.Message == "":
+		return e.Err.Error()
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+// Unwrap returns the cause, for errors.Is and errors.As.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode returns e.Code, implementing Coder.
+func (e *Error) ErrorCode() Code {
+	return e.Code
+}
+
+// Is reports whether target is e's code, so that errors.Is can match errors by code.
+func (e *Error) Is(target error) bool {
+	c, ok := target.(Code)
+	return ok && c != Unknown && c == e.Code
+}
+
+// Stack returns the stack captured by WithStack, one "function file:line" entry per frame, or nil.
+func (e *Error) Stack() []string {
+	if len(e.stack) == 0 {
+		return nil
+	}
+	var lines []string
+	frames := runtime.CallersFrames(e.stack)
+	for {
+		f, more := frames.Next()
+		lines = append(lines, f.Function+" "+f.File+":"+strconv.Itoa(f.Line))
+		if !more {
+			return lines
+		}
+	}
+}
+
+// Format implements fmt.Formatter. The %v and %s verbs print the same as Error; %+v adds the code,
+// the fields and the stack of each *Error in the chain, one per line.
+func (e *Error) Format(s fmt.State, verb rune) {
+	if verb != 'v' || !s.Flag('+') {
+		io.WriteString(s, e.Error())
+		return
+	}
+	for err := error(e); err != nil; err = errors.Unwrap(err) {
+		if err != error(e) {
+			io.WriteString(s, "\ncaused by: ")
+		}
+		ee, ok := err.(*Error)
+		if !ok {
+			io.WriteString(s, err.Error())
+			continue
+		}
+		io.WriteString(s, ee.Message)
+		if ee.Code != Unknown {
+			fmt.Fprintf(s, " [%s]", ee.Code)
+		}
+		for _, f := range ee.Fields {
+			fmt.Fprintf(s, " %s=%v", f.Key, f.Value)
+		}
+		for _, line := range ee.Stack() {
+			io.WriteString(s, "\n\t"+line)
+		}
+	}
+}
+
+// CodeOf returns the code of the first error in err's chain that implements Coder with a code other
+// than Unknown, or Unknown if there is none.
+func CodeOf(err error) Code {
+	for err != nil {
+		if c, ok := err.(Coder); ok && c.ErrorCode() != Unknown {
+			return c.ErrorCode()
+		}
+		err = errors.Unwrap(err)
+	}
+	return Unknown
+}
+
+// FieldsOf returns the fields of every *Error in err's chain, outermost first.
+func FieldsOf(err error) []Field {
+	var fs []Field
+	for err != nil {
+		if e, ok := err.(*Error); ok {
+			fs = append(fs, e.Fields...)
+		}
+		err = errors.Unwrap(err)
+	}
+	return fs
+}
+
+// jsonError is the JSON form of an *Error.
+type jsonError struct {
+	Code    Code           `json:"code,omitempty"`
+	Message string         `json:"message,omitempty"`
+	Fields  map[string]any `json:"fields,omitempty"`
+	Stack   []string       `json:"stack,omitempty"`
+	Cause   any            `json:"cause,omitempty"`
+}
+
+// MarshalJSON renders the error for structured logs. A cause that is an *Error is nested as an object
+// of its own; any other cause is rendered as its message. Field values that are errors are rendered
+// as their messages too, since most error types have no exported fields for encoding/json to use.
+func (e *Error) MarshalJSON() ([]byte, error) {
+	j := jsonError{Code: e.Code, Message: e.Message, Stack: e.Stack()}
+	if len(e.Fields) > 0 {
+		j.Fields = make(map[string]any, len(e.Fields))
+		for _, f := range e.Fields {
+			if err, ok := f.Value.(error); ok {
+				j.Fields[f.Key] = err.Error()
+			} else {
+				j.Fields[f.Key] = f.Value
+			}
+		}
+	}
+	if e.Err != nil {
+		if cause, ok := e.Err.(*Error); ok {
+			j.Cause = cause
+		} else {
+			j.Cause = e.Err.Error()
+		}
+	}
+	return json.Marshal(j)
+}
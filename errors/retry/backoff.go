@@ -0,0 +1,63 @@
+package retry
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff decides how long to wait before each retry.
+type Backoff interface {
+	// Delay returns the wait before retry number n, counting from 1, given the wait before the
+	// previous retry, which is zero for the first.
+	Delay(n int, prev time.Duration) time.Duration
+}
+
+// Constant waits d before every retry.
+func Constant(d time.Duration) Backoff {
+	return constant(d)
+}
+
+type constant time.Duration
+
+func (c constant) Delay(int, time.Duration) time.Duration {
+	return time.Duration(c)
+}
+
+// Exponential waits base before the first retry and doubles the wait for each retry after that, up
+// to ceiling.
+func Exponential(base, ceiling time.Duration) Backoff {
+	return exponential{base, ceiling}
+}
+
+type exponential struct {
+	base, ceiling time.Duration
+}
+
+func (e exponential) Delay(n int, _ time.Duration) time.Duration {
+	d := e.base
+	for i := 1; i < n && d < e.ceiling; i++ {
+		d *= 2
+	}
+	return min(d, e.ceiling)
+}
+
+// DecorrelatedJitter waits a random duration between base and three times the previous wait, up to
+// ceiling. The randomness stops many clients that failed together from retrying together, while the
+// waits still grow roughly exponentially. See "Exponential Backoff And Jitter" on the AWS
+// Architecture Blog.
+func DecorrelatedJitter(base, ceiling time.Duration) Backoff {
+	return decorrelated{base, ceiling}
+}
+
+type decorrelated struct {
+	base, ceiling time.Duration
+}
+
+func (d decorrelated) Delay(_ int, prev time.Duration) time.Duration {
+	prev = max(prev, d.base)
+	hi := min(3*prev, d.ceiling)
+	if hi <= d.base {
+		return hi
+	}
+	return d.base + rand.N(hi-d.base)
+}
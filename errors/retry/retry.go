@@ -0,0 +1,176 @@
+// Package retry calls a fallible function until it succeeds, waiting between attempts according to a
+// backoff policy. Attempts stop when the function succeeds, returns an error classified as permanent,
+// runs out of attempts or time, or when the context is done.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+// ErrExhausted is wrapped by the error Do returns when it runs out of attempts or time. The last
+// error from the function is wrapped too.
+var ErrExhausted = errors.New("retry: attempts exhausted")
+
+// Option configures Do.
+type Option func(*config)
+
+type config struct {
+	backoff     Backoff
+	maxAttempts int
+	maxElapsed  time.Duration
+	permanent   []func(error) bool
+	onRetry     func(n int, err error, delay time.Duration)
+	clock       clock.Clock
+}
+
+// WithBackoff sets the backoff policy. The default is Exponential(100*time.Millisecond, 10*time.Second).
+func WithBackoff(b Backoff) Option {
+	return func(c *config) {
+		c.backoff = b
+	}
+}
+
+// WithMaxAttempts sets the number of attempts, including the first. The default is 3; zero or less
+// means no limit.
+func WithMaxAttempts(n int) Option {
+	return func(c *config) {
+		c.maxAttempts = n
+	}
+}
+
+// WithMaxElapsed bounds the total time spent. Do gives up rather than wait for a retry that would
+// start after d has passed since the first attempt. The default is no limit.
+func WithMaxElapsed(d time.Duration) Option {
+	return func(c *config) {
+		c.maxElapsed = d
+	}
+}
+
+// WithPermanentIf classifies errors for which f returns true as permanent: Do returns them straight
+// away instead of retrying. It can be given more than once.
+func WithPermanentIf(f func(error) bool) Option {
+	return func(c *config) {
+		c.permanent = append(c.permanent, f)
+	}
+}
+
+// PermanentAs classifies errors as permanent if errors.As finds an E in their chain. For instance,
+// PermanentAs[*argError]() stops retrying as soon as the function reports a bad argument, since
+// trying again with the same argument can't help.
+func PermanentAs[E error]() Option {
+	return WithPermanentIf(func(err error) bool {
+		_, ok := errors.AsType[E](err)
+		return ok
+	})
+}
+
+// WithOnRetry sets a function called before each wait, with the number of the retry to come, the
+// error that caused it and how long Do is about to wait. It is useful for logging.
+func WithOnRetry(f func(n int, err error, delay time.Duration)) Option {
+	return func(c *config) {
+		c.onRetry = f
+	}
+}
+
+// WithClock sets the clock used to wait between attempts and to measure the time spent for
+// WithMaxElapsed. The default is the system clock.
+func WithClock(clk clock.Clock) Option {
+	return func(c *config) {
+		c.clock = clk
+	}
+}
+
+// Permanent marks err as not worth retrying. Do returns err itself, without the mark. It returns
+// nil if err is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Do calls fn until it returns nil, and returns nil. Otherwise it returns:
+//   - a permanent error, as soon as fn returns one;
+//   - an error wrapping both ErrExhausted and fn's last error, once attempts or time run out;
+//   - an error wrapping both ctx.Err() and fn's last error, if ctx is done while waiting to retry.
+func Do(ctx context.Context, fn func(ctx context.Context) error, opts ...Option) error {
+	c := config{
+		backoff:     Exponential(100*time.Millisecond, 10*time.Second),
+		maxAttempts: 3,
+		clock:       clock.Real(),
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	start := c.clock.Now()
+	var delay time.Duration
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if p, ok := errors.AsType[*permanentError](err); ok {
+			return p.err
+		}
+		if c.isPermanent(err) {
+			return err
+		}
+		if c.maxAttempts > 0 && attempt >= c.maxAttempts {
+			return fmt.Errorf("%w after %d attempts: %w", ErrExhausted, attempt, err)
+		}
+
+		delay = c.backoff.Delay(attempt, delay)
+		if c.maxElapsed > 0 && c.clock.Now().Add(delay).Sub(start) > c.maxElapsed {
+			return fmt.Errorf("%w after %d attempts in %v: %w", ErrExhausted, attempt, c.clock.Now().Sub(start), err)
+		}
+		if c.onRetry != nil {
+			c.onRetry(attempt, err, delay)
+		}
+
+		t := c.clock.NewTimer(delay)
+		select {
+		case <-t.Chan():
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("retry: %w after %d attempts: %w", ctx.Err(), attempt, err)
+		}
+	}
+}
+
+func (c *config) isPermanent(err error) bool {
+	for _, f := range c.permanent {
+		if f(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// DoValue is Do for functions that return a value as well as an error. It returns the value from the
+// successful call, or the zero value with the error.
+func DoValue[T any](ctx context.Context, fn func(ctx context.Context) (T, error), opts ...Option) (T, error) {
+	var v T
+	err := Do(ctx, func(ctx context.Context) error {
+		var err error
+		v, err = fn(ctx)
+		return err
+	}, opts...)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return v, nil
+}
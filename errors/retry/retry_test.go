@@ -0,0 +1,232 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+// argError mirrors the errors example's custom error type.
+type argError struct {
+	arg  int
+	prob string
+}
+
+func (e *argError) Error() string {
+	return fmt.Sprintf("%d - %s", e.arg, e.prob)
+}
+
+var errBusy = errors.New("busy")
+
+func TestConstant(t *testing.T) {
+	b := Constant(time.Second)
+	var prev time.Duration
+	for n := 1; n <= 5; n++ {
+		prev = b.Delay(n, prev)
+		if prev != time.Second {
+			t.Fatalf("Delay(%d) = %v, want 1s", n, prev)
+		}
+	}
+}
+
+func TestExponential(t *testing.T) {
+	b := Exponential(100*time.Millisecond, time.Second)
+	want := []time.Duration{
+		100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond,
+		time.Second, time.Second,
+	}
+	for i, w := range want {
+		if got := b.Delay(i+1, 0); got != w {
+			t.Errorf("Delay(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestDecorrelatedJitter(t *testing.T) {
+	const base, ceiling = 100 * time.Millisecond, 2 * time.Second
+	b := DecorrelatedJitter(base, ceiling)
+	var prev time.Duration
+	for n := 1; n <= 1000; n++ {
+		d := b.Delay(n, prev)
+		hi := min(3*max(prev, base), ceiling)
+		if d < base || d > hi {
+			t.Fatalf("Delay(%d, %v) = %v, want within [%v, %v]", n, prev, d, base, hi)
+		}
+		prev = d
+	}
+}
+
+// run calls Do on a new goroutine with the fake clock, returning a channel that receives its result
+// and a counter of the calls made to fn. fn fails with errBusy until the call numbered succeedOn, or
+// forever if succeedOn is zero.
+func run(ctx context.Context, fake *clock.Fake, succeedOn int32, opts ...Option) (<-chan error, *atomic.Int32) {
+	var calls atomic.Int32
+	done := make(chan error, 1)
+	go func() {
+		done <- Do(ctx, func(ctx context.Context) error {
+			if n := calls.Add(1); succeedOn == 0 || n < succeedOn {
+				return errBusy
+			}
+			return nil
+		}, append([]Option{WithClock(fake)}, opts...)...)
+	}()
+	return done, &calls
+}
+
+func receive(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("Do did not return")
+		return nil
+	}
+}
+
+func TestDoWaitsBetweenAttempts(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	var delays []time.Duration
+	done, calls := run(context.Background(), fake, 3,
+		WithBackoff(Exponential(time.Second, time.Minute)),
+		WithOnRetry(func(n int, err error, delay time.Duration) {
+			delays = append(delays, delay)
+		}))
+
+	fake.BlockUntil(1)
+	fake.Advance(999 * time.Millisecond)
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("calls before the first wait is over = %d, want 1", n)
+	}
+	fake.Advance(time.Millisecond)
+	fake.BlockUntil(1)
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("calls after the first wait = %d, want 2", n)
+	}
+	fake.Advance(2 * time.Second)
+
+	if err := receive(t, done); err != nil {
+		t.Fatalf("Do = %v", err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("calls = %d, want 3", n)
+	}
+	if len(delays) != 2 || delays[0] != time.Second || delays[1] != 2*time.Second {
+		t.Fatalf("delays = %v, want [1s 2s]", delays)
+	}
+}
+
+func TestDoMaxAttempts(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	done, calls := run(context.Background(), fake, 0,
+		WithBackoff(Constant(time.Second)), WithMaxAttempts(3))
+
+	for i := 0; i < 2; i++ {
+		fake.BlockUntil(1)
+		fake.Advance(time.Second)
+	}
+	err := receive(t, done)
+	if !errors.Is(err, ErrExhausted) || !errors.Is(err, errBusy) {
+		t.Fatalf("Do = %v, want ErrExhausted wrapping errBusy", err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("calls = %d, want 3", n)
+	}
+}
+
+func TestDoMaxElapsed(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	done, calls := run(context.Background(), fake, 0,
+		WithBackoff(Constant(3*time.Second)), WithMaxAttempts(0), WithMaxElapsed(10*time.Second))
+
+	// Attempts at 0s, 3s, 6s and 9s; one at 12s would be past the limit, so Do gives up without
+	// waiting for it.
+	for i := 0; i < 3; i++ {
+		fake.BlockUntil(1)
+		fake.Advance(3 * time.Second)
+	}
+	err := receive(t, done)
+	if !errors.Is(err, ErrExhausted) || !errors.Is(err, errBusy) {
+		t.Fatalf("Do = %v, want ErrExhausted wrapping errBusy", err)
+	}
+	if n := calls.Load(); n != 4 {
+		t.Fatalf("calls = %d, want 4", n)
+	}
+}
+
+func TestDoPermanentAs(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	calls := 0
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return fmt.Errorf("calling f2: %w", &argError{42, "can't work with it"})
+	}, WithClock(fake), PermanentAs[*argError]())
+
+	var ae *argError
+	if !errors.As(err, &ae) || ae.arg != 42 {
+		t.Fatalf("Do = %v, want the *argError", err)
+	}
+	if errors.Is(err, ErrExhausted) {
+		t.Fatalf("Do = %v, want no ErrExhausted for a permanent error", err)
+	}
+	if calls != 1 {
+		t.Fatalf("calls = %d, want 1", calls)
+	}
+}
+
+func TestDoPermanent(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	calls := 0
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return Permanent(errBusy)
+	}, WithClock(fake))
+
+	if err != errBusy {
+		t.Fatalf("Do = %v, want errBusy itself", err)
+	}
+	if calls != 1 {
+		t.Fatalf("calls = %d, want 1", calls)
+	}
+	if Permanent(nil) != nil {
+		t.Fatal("Permanent(nil) != nil")
+	}
+}
+
+func TestDoContextCancelledWhileWaiting(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	done, calls := run(ctx, fake, 0, WithBackoff(Constant(time.Hour)), WithMaxAttempts(0))
+
+	fake.BlockUntil(1)
+	cancel()
+	err := receive(t, done)
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, errBusy) {
+		t.Fatalf("Do = %v, want context.Canceled wrapping errBusy", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("calls = %d, want 1", n)
+	}
+}
+
+func TestDoValue(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	v, err := DoValue(context.Background(), func(ctx context.Context) (int, error) {
+		return 42, nil
+	}, WithClock(fake))
+	if err != nil || v != 42 {
+		t.Fatalf("DoValue = %v, %v, want 42, nil", v, err)
+	}
+
+	v, err = DoValue(context.Background(), func(ctx context.Context) (int, error) {
+		return 7, Permanent(errBusy)
+	}, WithClock(fake))
+	if err != errBusy || v != 0 {
+		t.Fatalf("DoValue = %v, %v, want 0, errBusy", v, err)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/yanw2/go-by-example
+
+go 1.26
@@ -6,6 +6,8 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/yanw2/go-by-example/mutexes/shardmap"
 )
 
 func main() {
@@ -68,4 +70,28 @@ func main() {
 
 	// Running the program shows that we executed about 90,000 total operations against our
 	// mutex-synchronized state.
+
+	sharded()
+}
+
+// A single mutex makes every reader wait for every writer, and for every other reader too. The
+// `shardmap` package splits the map into shards with a `sync.RWMutex` each, so goroutines only
+// contend when they touch the same shard, and readers of a shard run side by side.
+func sharded() {
+	m := shardmap.New[int, int]()
+	m.Store(1, 10)
+	if v, loaded := m.LoadOrStore(1, 20); loaded {
+		fmt.Println("already stored:", v)
+	}
+	m.Store(2, 20)
+	m.Delete(1)
+	m.Range(func(k, v int) bool {
+		fmt.Println("entry:", k, v)
+		return true
+	})
+	fmt.Println("len:", m.Len())
+
+	// The benchmarks in `shardmap/shardmap_test.go` compare the sharded map with a map behind a single
+	// mutex, under this example's mix of one write for every ten reads. Run them with
+	// `go test -bench . -cpu 1,4 ./mutexes/shardmap`.
 }
@@ -0,0 +1,161 @@
+// Package shardmap provides a concurrent map split into shards, each guarded by its own
+// sync.RWMutex. The mutexes example puts a whole map behind one mutex, so every reader and writer
+// waits for every other; here goroutines only contend when their keys hash to the same shard, and
+// readers of a shard don't block each other at all.
+package shardmap
+
+import (
+	"hash/maphash"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// Option configures a Map.
+type Option func(*config)
+
+type config struct {
+	shards int
+}
+
+// WithShards sets the number of shards, rounded up to a power of two. The default is four per CPU.
+func WithShards(n int) Option {
+	return func(c *config) {
+		c.shards = n
+	}
+}
+
+// shardSize is the size each shard is padded to. Every Lock and Unlock writes to the shard's mutex,
+// so two shards sharing a cache line would make goroutines on different shards contend after all.
+// 128 bytes is a whole line on CPUs with 128-byte lines and a pair of lines on those that prefetch
+// 64-byte lines two at a time.
+const shardSize = 128
+
+// shard is one part of the map. A map value is a single pointer whatever its key and value types,
+// so the padding is the same for every Map.
+type shard[K comparable, V any] struct {
+	mu sync.RWMutex
+	m  map[K]V
+	_  [shardSize - unsafe.Sizeof(sync.RWMutex{}) - unsafe.Sizeof(map[int]int(nil))]byte
+}
+
+// Map is a generic map that is safe for concurrent use. The zero value is not usable; create one
+// with New.
+type Map[K comparable, V any] struct {
+	seed   maphash.Seed
+	mask   uint64
+	shards []shard[K, V]
+}
+
+// New returns an empty map.
+func New[K comparable, V any](opts ...Option) *Map[K, V] {
+	c := config{shards: 4 * runtime.GOMAXPROCS(0)}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	n := 1
+	for n < c.shards {
+		n <<= 1
+	}
+
+	m := &Map[K, V]{
+		seed:   maphash.MakeSeed(),
+		mask:   uint64(n - 1),
+		shards: make([]shard[K, V], n),
+	}
+	for i := range m.shards {
+		m.shards[i].m = make(map[K]V)
+	}
+	return m
+}
+
+func (m *Map[K, V]) shard(key K) *shard[K, V] {
+	return &m.shards[maphash.Comparable(m.seed, key)&m.mask]
+}
+
+// Load returns the value stored under key, and whether there was one.
+func (m *Map[K, V]) Load(key K) (value V, ok bool) {
+	s := m.shard(key)
+	s.mu.RLock()
+	value, ok = s.m[key]
+	s.mu.RUnlock()
+	return value, ok
+}
+
+// Store sets the value for key.
+func (m *Map[K, V]) Store(key K, value V) {
+	s := m.shard(key)
+	s.mu.Lock()
+	s.m[key] = value
+	s.mu.Unlock()
+}
+
+// LoadOrStore returns the existing value for key if there is one. Otherwise it stores and returns
+// value. loaded reports whether the value was already there.
+func (m *Map[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	s := m.shard(key)
+
+	// Most calls for a key find it already stored, so try with the read lock first.
+	s.mu.RLock()
+	actual, loaded = s.m[key]
+	s.mu.RUnlock()
+	if loaded {
+		return actual, true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Another goroutine may have stored the key between the two locks.
+	if actual, loaded = s.m[key]; loaded {
+		return actual, true
+	}
+	s.m[key] = value
+	return value, false
+}
+
+// Delete removes the value for key, if any.
+func (m *Map[K, V]) Delete(key K) {
+	s := m.shard(key)
+	s.mu.Lock()
+	delete(s.m, key)
+	s.mu.Unlock()
+}
+
+// Range calls f for each key and value in the map until f returns false. Each shard is copied under
+// its read lock and f runs without any lock held, so f may use the map itself. As with sync.Map,
+// Range is not a consistent snapshot: changes made while it runs may or may not be seen.
+func (m *Map[K, V]) Range(f func(key K, value V) bool) {
+	type entry struct {
+		k K
+		v V
+	}
+	var entries []entry
+	for i := range m.shards {
+		s := &m.shards[i]
+		entries = entries[:0]
+		s.mu.RLock()
+		for k, v := range s.m {
+			entries = append(entries, entry{k, v})
+		}
+		s.mu.RUnlock()
+
+		for _, e := range entries {
+			if !f(e.k, e.v) {
+				return
+			}
+		}
+	}
+}
+
+// Len returns the number of entries in the map. The shards are counted one at a time, so under
+// concurrent writes the result is only approximate.
+func (m *Map[K, V]) Len() int {
+	n := 0
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.RLock()
+		n += len(s.m)
+		s.mu.RUnlock()
+	}
+	return n
+}
@@ -0,0 +1,162 @@
+package shardmap
+
+import (
+	"fmt"
+	"maps"
+	"math/rand"
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+func TestLoadStoreDelete(t *testing.T) {
+	m := New[string, int]()
+	if v, ok := m.Load("a"); ok || v != 0 {
+		t.Fatalf("Load on an empty map = %d, %t, want 0, false", v, ok)
+	}
+
+	m.Store("a", 1)
+	m.Store("a", 2)
+	if v, ok := m.Load("a"); !ok || v != 2 {
+		t.Fatalf("Load after Store = %d, %t, want 2, true", v, ok)
+	}
+
+	m.Delete("a")
+	m.Delete("missing")
+	if v, ok := m.Load("a"); ok || v != 0 {
+		t.Fatalf("Load after Delete = %d, %t, want 0, false", v, ok)
+	}
+}
+
+func TestLoadOrStore(t *testing.T) {
+	m := New[string, int]()
+	if v, loaded := m.LoadOrStore("a", 1); loaded || v != 1 {
+		t.Fatalf("first LoadOrStore = %d, %t, want 1, false", v, loaded)
+	}
+	if v, loaded := m.LoadOrStore("a", 2); !loaded || v != 1 {
+		t.Fatalf("second LoadOrStore = %d, %t, want 1, true", v, loaded)
+	}
+}
+
+func TestConcurrentLoadOrStoreStoresOnce(t *testing.T) {
+	m := New[int, int](WithShards(2))
+	var wg sync.WaitGroup
+	winners := make(chan int, 100)
+	for g := 0; g < 100; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, loaded := m.LoadOrStore(1, g); !loaded {
+				winners <- g
+			}
+		}()
+	}
+	wg.Wait()
+	close(winners)
+
+	if len(winners) != 1 {
+		t.Fatalf("%d goroutines stored a value, want 1", len(winners))
+	}
+	if v, _ := m.Load(1); v != <-winners {
+		t.Fatalf("Load = %d, want the value stored by LoadOrStore", v)
+	}
+}
+
+func TestRangeAndLen(t *testing.T) {
+	m := New[int, string](WithShards(4))
+	want := make(map[int]string)
+	for i := 0; i < 100; i++ {
+		m.Store(i, fmt.Sprint(i))
+		want[i] = fmt.Sprint(i)
+	}
+	if n := m.Len(); n != 100 {
+		t.Fatalf("Len = %d, want 100", n)
+	}
+
+	got := make(map[int]string)
+	m.Range(func(k int, v string) bool {
+		got[k] = v
+		return true
+	})
+	if !maps.Equal(got, want) {
+		t.Fatalf("Range saw %d entries, want the 100 stored", len(got))
+	}
+
+	calls := 0
+	m.Range(func(k int, v string) bool {
+		calls++
+		return calls < 3
+	})
+	if calls != 3 {
+		t.Fatalf("Range called f %d times after it returned false on the third, want 3", calls)
+	}
+
+	// f runs without a lock held, so it may change the map.
+	m.Range(func(k int, v string) bool {
+		m.Delete(k)
+		return true
+	})
+	if n := m.Len(); n != 0 {
+		t.Fatalf("Len after deleting from Range = %d, want 0", n)
+	}
+}
+
+func TestShardsRoundUpToAPowerOfTwo(t *testing.T) {
+	m := New[int, int](WithShards(5))
+	if n := len(m.shards); n != 8 {
+		t.Fatalf("WithShards(5) made %d shards, want 8", n)
+	}
+}
+
+func TestShardIsPadded(t *testing.T) {
+	if size := unsafe.Sizeof(shard[string, [4]int]{}); size != shardSize {
+		t.Fatalf("shard is %d bytes, want %d", size, shardSize)
+	}
+}
+
+// The benchmarks run the mutexes example's mix of one write for every ten reads, first over its five
+// keys and then over a thousand, against a map behind a single mutex and against the sharded map.
+// With only five keys most of the shards sit idle, so the read locks do most of the work; with more
+// keys the writers spread out too. The gap grows with the number of CPUs, so run them with something
+// like -cpu 1,4. On a single CPU there is no contention to avoid, and the cost of hashing keys makes
+// the sharded map slightly slower.
+var benchKeys = []int{5, 1000}
+
+func BenchmarkSingleMutex(b *testing.B) {
+	for _, keys := range benchKeys {
+		b.Run(fmt.Sprintf("keys=%d", keys), func(b *testing.B) {
+			state := make(map[int]int)
+			var mutex sync.Mutex
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					key := rand.Intn(keys)
+					mutex.Lock()
+					if i%11 == 0 {
+						state[key] = i
+					} else {
+						_ = state[key]
+					}
+					mutex.Unlock()
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkSharded(b *testing.B) {
+	for _, keys := range benchKeys {
+		b.Run(fmt.Sprintf("keys=%d", keys), func(b *testing.B) {
+			m := New[int, int]()
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					key := rand.Intn(keys)
+					if i%11 == 0 {
+						m.Store(key, i)
+					} else {
+						m.Load(key)
+					}
+				}
+			})
+		})
+	}
+}
@@ -1,16 +1,87 @@
 package main
 
-import "os"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yanw2/go-by-example/errors/errs"
+	"github.com/yanw2/go-by-example/panic/supervise"
+)
 
 func main() {
+	creating()
+	recovering()
+	supervising()
+
 	// A `panic` typically means something went unexpectedly wrong. Mostly we use it to fail fast on errors that
 	// shouldn't occur during normal operation, or that we aren't prepared to handle gracefully.
 	panic("a problem")
+}
+
+// A common use of `panic` is to abort if a function returns an error value that we don't know how to
+// (or want to) handle. Here's an example of panicking if we get an unexpected error when creating a new
+// file. The directory doesn't exist, so creating the file always fails; `supervise.Catch`, shown below,
+// stops the panic so that the rest of the example still runs.
+func creating() {
+	err := supervise.Catch(func() error {
+		_, err := os.Create("/nonexistent/file")
+		if err != nil {
+			panic(err)
+		}
+		return nil
+	})
+	fmt.Println("create:", err)
+}
 
-	// A common use of `panic` is to abort if a function returns an error valuethat we don't know how to
-	// (or want to) handle. Here's an example of panicking if we get an unexpected error when creating a new file.
-	_, err := os.Create("/tmp/file")
-	if err != nil {
-		panic(err)
+// A panic in any goroutine crashes the whole program, not just that goroutine. `supervise.Catch`
+// recovers a panic with `recover` in a deferred function and turns it into a `*supervise.PanicError`
+// carrying the panic value and stack, and `supervise.Go` does the same for a new goroutine.
+func recovering() {
+	err := supervise.Catch(func() error {
+		var m map[string]int
+		m["a"] = 1
+		return nil
+	})
+	fmt.Println("caught:", err)
+
+	// A panic with an error value can be inspected with `errors.Is` and `errors.As` like any other
+	// wrapped error, and carries the `errs.Internal` code.
+	err = <-supervise.Go(func() error {
+		panic(os.ErrNotExist)
+	})
+	var pe *supervise.PanicError
+	if errors.As(err, &pe) {
+		fmt.Println("not exist:", errors.Is(err, os.ErrNotExist), "code:", errs.CodeOf(err))
+		fmt.Println("stack:", strings.SplitN(string(pe.Stack), "\n", 2)[0])
 	}
 }
+
+// A supervisor runs goroutines and restarts them when they fail. Under `OneForOne` only the failed
+// child is restarted; under `OneForAll` the others are stopped and restarted with it. If children
+// keep failing faster than the restart intensity allows, the supervisor gives up.
+func supervising() {
+	sv := supervise.New(
+		supervise.WithStrategy(supervise.OneForAll),
+		supervise.WithIntensity(2, time.Second),
+		supervise.WithOnFailure(func(name string, err error) {
+			fmt.Println("child failed:", name, err)
+		}))
+
+	starts := 0
+	sv.Add("flaky", supervise.Transient, func(ctx context.Context) error {
+		starts++
+		panic(fmt.Sprintf("start %d went wrong", starts))
+	})
+	sv.Add("steady", supervise.Permanent, func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	err := sv.Run(context.Background())
+	fmt.Println("supervisor:", err)
+	fmt.Println("gave up:", errors.Is(err, supervise.ErrIntensity))
+}
@@ -0,0 +1,58 @@
+// Package supervise keeps a panicking goroutine from taking the whole program down with it.
+//
+// Catch and Go recover a panic and turn it into a *PanicError carrying the panic value and the stack
+// where it happened. A Supervisor goes further and restarts the goroutines it runs when they fail,
+// in the style of Erlang supervisors, up to a limit on how often.
+package supervise
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/yanw2/go-by-example/errors/errs"
+)
+
+// PanicError is the error a recovered panic is turned into.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack of the panicking goroutine, as from runtime/debug.Stack.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error, so that errors.Is and errors.As see through a
+// panic(err).
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// ErrorCode returns errs.Internal, implementing errs.Coder: a panic is a bug rather than a problem
+// with the input.
+func (e *PanicError) ErrorCode() errs.Code {
+	return errs.Internal
+}
+
+// Catch calls fn and returns its error, or a *PanicError if fn panics.
+func Catch(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
+
+// Go runs fn in a new goroutine, recovering it if it panics. The returned channel receives fn's error,
+// or a *PanicError, once fn is done; it is buffered, so it needn't be read.
+func Go(fn func() error) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- Catch(fn)
+	}()
+	return done
+}
@@ -0,0 +1,246 @@
+package supervise
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+// ErrIntensity is wrapped by the error Run returns when children fail more often than the restart
+// intensity allows. The failure that went over the limit is wrapped too.
+var ErrIntensity = errors.New("supervise: restart intensity exceeded")
+
+// ErrRunning is returned by Add and Run once Run has been called.
+var ErrRunning = errors.New("supervise: supervisor already running")
+
+// Strategy says which children a supervisor restarts when one of them fails.
+type Strategy int
+
+const (
+	// OneForOne restarts only the child that failed. This is the default.
+	OneForOne Strategy = iota
+	// OneForAll stops every other child and then restarts them all, for children that depend on each
+	// other and can't carry on without the one that failed.
+	OneForAll
+)
+
+// Restart says when a child is restarted after it returns.
+type Restart int
+
+const (
+	// Transient children are restarted if they return an error or panic, but not if they return nil.
+	// This is the default.
+	Transient Restart = iota
+	// Permanent children are always restarted, even if they return nil.
+	Permanent
+	// Temporary children are never restarted.
+	Temporary
+)
+
+// Option configures a Supervisor.
+type Option func(*Supervisor)
+
+// WithStrategy sets the restart strategy.
+func WithStrategy(s Strategy) Option {
+	return func(sv *Supervisor) {
+		sv.strategy = s
+	}
+}
+
+// WithIntensity limits restarts to n within any period. If a child fails when n restarts have already
+// happened within the period, the supervisor stops every child and Run returns an ErrIntensity error,
+// on the grounds that restarting is not fixing the problem. The default is 3 restarts in 5 seconds.
+func WithIntensity(n int, period time.Duration) Option {
+	return func(sv *Supervisor) {
+		sv.maxRestarts, sv.period = n, period
+	}
+}
+
+// WithOnFailure sets a function called with the name and error of each child that fails, whether or
+// not it is restarted. It is useful for logging. It runs on the supervisor's goroutine, so it should
+// return quickly.
+func WithOnFailure(f func(name string, err error)) Option {
+	return func(sv *Supervisor) {
+		sv.onFailure = f
+	}
+}
+
+// WithClock sets the clock used to measure restart intensity. The default is the system clock.
+func WithClock(clk clock.Clock) Option {
+	return func(sv *Supervisor) {
+		sv.clock = clk
+	}
+}
+
+type child struct {
+	name    string
+	fn      func(ctx context.Context) error
+	restart Restart
+
+	// The fields below are only used by Run's goroutine.
+	running bool
+	done    bool
+	cancel  context.CancelFunc
+}
+
+type exit struct {
+	c   *child
+	err error
+}
+
+// Supervisor runs a set of child goroutines and restarts them when they fail. Children are added with
+// Add before the supervisor is started with Run.
+type Supervisor struct {
+	strategy    Strategy
+	maxRestarts int
+	period      time.Duration
+	onFailure   func(name string, err error)
+	clock       clock.Clock
+
+	children []*child
+	started  bool
+	restarts []time.Time
+	exits    chan exit
+}
+
+// New returns a supervisor with no children.
+func New(opts ...Option) *Supervisor {
+	sv := &Supervisor{
+		maxRestarts: 3,
+		period:      5 * time.Second,
+		clock:       clock.Real(),
+		exits:       make(chan exit),
+	}
+	for _, opt := range opts {
+		opt(sv)
+	}
+	return sv
+}
+
+// Add adds a child with the given name and restart policy. The child should return once its context
+// is cancelled, which happens when the supervisor stops or, under OneForAll, when another child
+// fails. Add must not be called concurrently with itself or Run.
+func (sv *Supervisor) Add(name string, restart Restart, fn func(ctx context.Context) error) error {
+	if sv.started {
+		return ErrRunning
+	}
+	sv.children = append(sv.children, &child{name: name, fn: fn, restart: restart})
+	return nil
+}
+
+// Run starts the children and supervises them until ctx is done, every child has finished for good,
+// or the restart intensity is exceeded. Before returning it cancels every child still running and
+// waits for them to return. It returns nil unless the intensity was exceeded.
+func (sv *Supervisor) Run(ctx context.Context) error {
+	if sv.started {
+		return ErrRunning
+	}
+	sv.started = true
+
+	for _, c := range sv.children {
+		sv.start(ctx, c)
+	}
+	defer sv.stopAll()
+
+	for {
+		if !sv.anyRunning() {
+			return nil
+		}
+		var e exit
+		select {
+		case e = <-sv.exits:
+		case <-ctx.Done():
+			return nil
+		}
+
+		c := e.c
+		c.running = false
+		c.cancel()
+		if e.err != nil && sv.onFailure != nil {
+			sv.onFailure(c.name, e.err)
+		}
+		if !c.shouldRestart(e.err) {
+			c.done = true
+			continue
+		}
+		if !sv.allowRestart() {
+			return fmt.Errorf("%w: child %s: %w", ErrIntensity, c.name, e.err)
+		}
+
+		if sv.strategy == OneForAll {
+			sv.stopAll()
+			for _, other := range sv.children {
+				// A Temporary child stopped because of another's failure is done for good too.
+				if other != c && other.restart == Temporary {
+					other.done = true
+				}
+				if !other.done {
+					sv.start(ctx, other)
+				}
+			}
+		} else {
+			sv.start(ctx, c)
+		}
+	}
+}
+
+func (c *child) shouldRestart(err error) bool {
+	switch c.restart {
+	case Permanent:
+		return true
+	case Transient:
+		return err != nil
+	}
+	return false
+}
+
+// allowRestart records a restart, reporting whether it is within the restart intensity.
+func (sv *Supervisor) allowRestart() bool {
+	now := sv.clock.Now()
+	recent := sv.restarts[:0]
+	for _, t := range sv.restarts {
+		if now.Sub(t) < sv.period {
+			recent = append(recent, t)
+		}
+	}
+	sv.restarts = append(recent, now)
+	return len(sv.restarts) <= sv.maxRestarts
+}
+
+func (sv *Supervisor) start(ctx context.Context, c *child) {
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.running = true
+	go func() {
+		err := Catch(func() error {
+			return c.fn(ctx)
+		})
+		sv.exits <- exit{c, err}
+	}()
+}
+
+func (sv *Supervisor) anyRunning() bool {
+	for _, c := range sv.children {
+		if c.running {
+			return true
+		}
+	}
+	return false
+}
+
+// stopAll cancels every running child and waits for them to return. Their exits are not failures, so
+// they don't count towards the intensity.
+func (sv *Supervisor) stopAll() {
+	for _, c := range sv.children {
+		if c.running {
+			c.cancel()
+		}
+	}
+	for sv.anyRunning() {
+		e := <-sv.exits
+		e.c.running = false
+		e.c.cancel()
+	}
+}
@@ -0,0 +1,225 @@
+package supervise
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+var errBoom = errors.New("boom")
+
+// probe is a child that reports each start and fails whenever the test sends on fail. Otherwise it
+// runs until its context is cancelled.
+type probe struct {
+	mu      sync.Mutex
+	starts  int
+	started chan struct{}
+	fail    chan error
+}
+
+func newProbe() *probe {
+	return &probe{started: make(chan struct{}, 16), fail: make(chan error)}
+}
+
+func (p *probe) run(ctx context.Context) error {
+	p.mu.Lock()
+	p.starts++
+	p.mu.Unlock()
+	p.started <- struct{}{}
+	select {
+	case err := <-p.fail:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *probe) startCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.starts
+}
+
+// waitStarted waits for the probe to report n more starts.
+func (p *probe) waitStarted(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-p.started:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for a child to start")
+		}
+	}
+}
+
+// runAsync runs the supervisor on its own goroutine, returning the channel Run's error is sent on.
+func runAsync(ctx context.Context, sv *Supervisor) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- sv.Run(ctx) }()
+	return done
+}
+
+func wait(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for Run to return")
+		return nil
+	}
+}
+
+func TestOneForOneRestartsOnlyTheFailedChild(t *testing.T) {
+	var failures []string
+	sv := New(WithClock(clock.NewFake(time.Now())), WithOnFailure(func(name string, err error) {
+		failures = append(failures, name)
+	}))
+	a, b := newProbe(), newProbe()
+	sv.Add("a", Transient, a.run)
+	sv.Add("b", Permanent, b.run)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runAsync(ctx, sv)
+	a.waitStarted(t, 1)
+	b.waitStarted(t, 1)
+
+	a.fail <- errBoom
+	a.waitStarted(t, 1)
+
+	cancel()
+	if err := wait(t, done); err != nil {
+		t.Fatalf("Run = %v, want nil", err)
+	}
+	if a.startCount() != 2 || b.startCount() != 1 {
+		t.Fatalf("starts: a %d, b %d, want a 2, b 1", a.startCount(), b.startCount())
+	}
+	if len(failures) != 1 || failures[0] != "a" {
+		t.Fatalf("failures = %q, want [a]", failures)
+	}
+}
+
+func TestRestartPolicies(t *testing.T) {
+	sv := New(WithClock(clock.NewFake(time.Now())))
+	transient, permanent, temporary := newProbe(), newProbe(), newProbe()
+	sv.Add("transient", Transient, transient.run)
+	sv.Add("permanent", Permanent, permanent.run)
+	sv.Add("temporary", Temporary, temporary.run)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := runAsync(ctx, sv)
+	transient.waitStarted(t, 1)
+	permanent.waitStarted(t, 1)
+	temporary.waitStarted(t, 1)
+
+	// A Permanent child is restarted even when it returns nil.
+	permanent.fail <- nil
+	permanent.waitStarted(t, 1)
+
+	// A Transient child returning nil and a failing Temporary child are done for good.
+	transient.fail <- nil
+	temporary.fail <- errBoom
+	permanent.fail <- errBoom
+	permanent.waitStarted(t, 1)
+
+	cancel()
+	if err := wait(t, done); err != nil {
+		t.Fatalf("Run = %v, want nil", err)
+	}
+	if transient.startCount() != 1 || permanent.startCount() != 3 || temporary.startCount() != 1 {
+		t.Fatalf("starts: transient %d, permanent %d, temporary %d, want 1, 3, 1",
+			transient.startCount(), permanent.startCount(), temporary.startCount())
+	}
+}
+
+func TestRunReturnsOnceEveryChildIsDone(t *testing.T) {
+	sv := New(WithClock(clock.NewFake(time.Now())))
+	a := newProbe()
+	sv.Add("a", Transient, a.run)
+
+	done := runAsync(context.Background(), sv)
+	a.waitStarted(t, 1)
+	a.fail <- nil
+	if err := wait(t, done); err != nil {
+		t.Fatalf("Run = %v, want nil", err)
+	}
+	if err := sv.Run(context.Background()); !errors.Is(err, ErrRunning) {
+		t.Fatalf("second Run = %v, want ErrRunning", err)
+	}
+}
+
+func TestOneForAllRestartsEveryChildButTemporaryOnes(t *testing.T) {
+	var failures []string
+	sv := New(WithClock(clock.NewFake(time.Now())), WithStrategy(OneForAll),
+		WithOnFailure(func(name string, err error) {
+			failures = append(failures, name)
+		}))
+	a, b, temp := newProbe(), newProbe(), newProbe()
+	sv.Add("a", Transient, a.run)
+	sv.Add("b", Permanent, b.run)
+	sv.Add("temp", Temporary, temp.run)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runAsync(ctx, sv)
+	a.waitStarted(t, 1)
+	b.waitStarted(t, 1)
+	temp.waitStarted(t, 1)
+
+	a.fail <- errBoom
+	a.waitStarted(t, 1)
+	b.waitStarted(t, 1)
+
+	cancel()
+	if err := wait(t, done); err != nil {
+		t.Fatalf("Run = %v, want nil", err)
+	}
+	if a.startCount() != 2 || b.startCount() != 2 || temp.startCount() != 1 {
+		t.Fatalf("starts: a %d, b %d, temp %d, want 2, 2, 1", a.startCount(), b.startCount(), temp.startCount())
+	}
+	// Children stopped because of a's failure didn't fail themselves.
+	if len(failures) != 1 || failures[0] != "a" {
+		t.Fatalf("failures = %q, want [a]", failures)
+	}
+}
+
+func TestRestartIntensity(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	sv := New(WithClock(fake), WithIntensity(2, time.Second))
+	a := newProbe()
+	sv.Add("a", Permanent, a.run)
+
+	done := runAsync(context.Background(), sv)
+	a.waitStarted(t, 1)
+
+	// Restarts 600ms apart never put more than two within a second.
+	for i := 0; i < 5; i++ {
+		fake.Advance(600 * time.Millisecond)
+		a.fail <- errBoom
+		a.waitStarted(t, 1)
+	}
+
+	// Another failure straight away would make three restarts within the second.
+	a.fail <- errBoom
+	err := wait(t, done)
+	if !errors.Is(err, ErrIntensity) || !errors.Is(err, errBoom) {
+		t.Fatalf("Run = %v, want ErrIntensity wrapping the last failure", err)
+	}
+	if got := a.startCount(); got != 6 {
+		t.Fatalf("starts = %d, want 6", got)
+	}
+}
+
+func TestAddAfterRunFails(t *testing.T) {
+	sv := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sv.Run(ctx)
+	if err := sv.Add("late", Transient, func(ctx context.Context) error { return nil }); !errors.Is(err, ErrRunning) {
+		t.Fatalf("Add after Run = %v, want ErrRunning", err)
+	}
+}
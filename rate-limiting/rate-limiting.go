@@ -1,13 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+	"github.com/yanw2/go-by-example/rate-limiting/ratelimit"
 )
 
 func main() {
 	// `Rate limiting` is an important mechanism for controlling resource utilization and maintaning
 	// quality of service. Go elegantly supports rate limiting with goroutines, channels, and tickers.
+	// Here we use the token bucket `Limiter` from the `ratelimit` package. The limiters take their time
+	// from a `clock.Clock`; we use the real clock here and a fake one further down.
+	clk := clock.Real()
 
 	// Suppose we want to limit our handling of incoming requests. We'll serve these requests off a channel
 	// of the same name.
@@ -17,33 +26,25 @@ func main() {
 	}
 	close(requests)
 
-	// This `limiter` channel will receive a value every 200 milliseconds. This is the regulator in our rate
+	// This `limiter` hands out one token every 200 milliseconds. This is the regulator in our rate
 	// limiting scheme.
-	limiter := time.Tick(200 * time.Millisecond)
+	limiter := ratelimit.New(ratelimit.Every(200*time.Millisecond), 1, ratelimit.WithClock(clk))
+	defer limiter.Stop()
 
-	// By blocking on a receive from the `limiter` channel before serving each request, we limit ourselves
-	// to 1 request every 200 milliseconds.
+	// By blocking in `Wait` before serving each request, we limit ourselves to 1 request every 200
+	// milliseconds.
+	ctx := context.Background()
 	for req := range requests {
-		<-limiter
-		fmt.Println("request", req, time.Now())
+		limiter.Wait(ctx)
+		fmt.Println("request", req, clk.Now())
 	}
 
 	// We may want to allow short bursts of requests in our rate limiting scheme while preserving the overall
-	// rate limit. We can accomplish this by buffering our limiter channel. This `burstyLimiter` channel will
-	// allow bursts of up to 3 events.
-	burstyLimiter := make(chan time.Time, 3)
-
-	// Fill up the channel to represent allowed bursting.
-	for i := 0; i < 3; i++ {
-		burstyLimiter <- time.Now()
-	}
-
-	// Every 200 milliseconds we'll try to add a new value to burstyLimiter, up to its limit of 3.
-	go func() {
-		for t := range time.Tick(200 * time.Millisecond) {
-			burstyLimiter <- t
-		}
-	}()
+	// rate limit. We can accomplish this by giving the bucket room for more than one token. This
+	// `burstyLimiter` will allow bursts of up to 3 events. Its bucket starts full and is refilled lazily,
+	// so unlike a ticker feeding a channel there is no goroutine to leak.
+	burstyLimiter := ratelimit.New(ratelimit.Every(200*time.Millisecond), 3, ratelimit.WithClock(clk))
+	defer burstyLimiter.Stop()
 
 	// Now simulate 5 more incoming requests. The first 3 of these will benefit from the burst capability of
 	// burstyLimiter.
@@ -53,11 +54,81 @@ func main() {
 	}
 	close(burstyRequests)
 	for req := range burstyRequests {
-		<-burstyLimiter
-		fmt.Println("request", req, time.Now())
+		burstyLimiter.Wait(ctx)
+		fmt.Println("request", req, clk.Now())
 	}
 
 	// Running our program we see the first batch of requests handled once every ~200 milliseconds as desired.
 	// For the second batch of requests we serve the first 3 immediately because of the burstable rate limiting,
 	// then serve the remaining 2 with ~200ms delays each.
+
+	// Instead of waiting, `Allow` reports whether a request may proceed right now, and `Reserve` takes a
+	// token and tells us how long we would have to wait for it.
+	fmt.Println("allowed:", burstyLimiter.Allow())
+	fmt.Println(burstyLimiter.Reserve())
+
+	// The rate and burst can be changed while the limiter is in use.
+	burstyLimiter.SetRate(ratelimit.Every(50 * time.Millisecond))
+	burstyLimiter.SetBurst(5)
+	fmt.Println(burstyLimiter.Reserve())
+
+	// Once stopped, a limiter wakes any waiters and refuses further requests.
+	burstyLimiter.Stop()
+	fmt.Println(burstyLimiter.Wait(ctx))
+
+	// Often each client should get its own allowance. A `KeyedLimiter` keeps a separate bucket per key
+	// and counts the allowed and denied requests for each. Buckets that sit idle for longer than the TTL
+	// are evicted so that memory stays bounded by the number of active clients.
+	clients := ratelimit.NewKeyed[string](ratelimit.Every(time.Second), 2, time.Minute)
+	defer clients.Stop()
+	for _, client := range []string{"alice", "bob", "alice", "alice", "bob", "carol"} {
+		fmt.Println(client, "allowed:", clients.Allow(client))
+	}
+	fmt.Println(clients.AllStats())
+
+	// A token bucket only approximates a contract like "3 requests per rolling minute". The package also
+	// offers a sliding window log, which enforces it exactly, a sliding window counter, which estimates
+	// it in constant memory, and a leaky bucket, which spaces requests out evenly. They all implement
+	// `ratelimit.RateLimiter`, so they can be swapped freely. To compare them without waiting for real
+	// minutes to pass, we give each the same `clock.Fake` and move time forward ourselves.
+	fake := clock.NewFake(time.Now())
+	algorithms := []struct {
+		name    string
+		limiter ratelimit.RateLimiter
+	}{
+		{"token bucket  ", ratelimit.New(ratelimit.Every(20*time.Second), 3, ratelimit.WithClock(fake))},
+		{"sliding log   ", ratelimit.NewSlidingLog(3, time.Minute, ratelimit.WithClock(fake))},
+		{"sliding window", ratelimit.NewSlidingWindow(3, time.Minute, ratelimit.WithClock(fake))},
+		{"leaky bucket  ", ratelimit.NewLeakyBucket(ratelimit.Every(20*time.Second), 3, ratelimit.WithClock(fake))},
+	}
+	start := fake.Now()
+	for _, a := range algorithms {
+		fake.Set(start)
+		var allowed []bool
+		for _, step := range []time.Duration{0, 0, 0, 0, 30 * time.Second, 0, 20 * time.Second, 0, 10 * time.Second, 0} {
+			fake.Advance(step)
+			allowed = append(allowed, a.limiter.Allow())
+		}
+		fmt.Println(a.name, allowed)
+		a.limiter.Stop()
+	}
+
+	// `ratelimit.Middleware` puts a `KeyedLimiter` in front of an `http.Handler`. Here each API key gets
+	// 2 requests per second; throttled requests are answered with 429 and a `Retry-After` header and
+	// never reach our handler. We use `httptest` to exercise it without opening a socket.
+	apiLimiter := ratelimit.NewKeyed[string](ratelimit.Every(500*time.Millisecond), 2, time.Minute)
+	defer apiLimiter.Stop()
+	hello := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "hello")
+	})
+	handler := ratelimit.Middleware(apiLimiter, ratelimit.KeyByHeader("X-API-Key"))(hello)
+	for i := 1; i <= 3; i++ {
+		req := httptest.NewRequest("GET", "/hello", nil)
+		req.Header.Set("X-API-Key", "secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		h := rec.Header()
+		fmt.Println("response", i, rec.Code, "limit", h.Get("X-RateLimit-Limit"),
+			"remaining", h.Get("X-RateLimit-Remaining"), "retry after", h.Get("Retry-After"))
+	}
 }
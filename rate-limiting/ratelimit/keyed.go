@@ -0,0 +1,219 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+// KeyStats counts the events allowed and denied for a single key.
+type KeyStats struct {
+	Allowed uint64
+	Denied  uint64
+}
+
+// KeyedLimiter keeps an independent token bucket per key, such as a client ID, IP address or tenant.
+// Buckets that have not been used for longer than the TTL are evicted by a background goroutine, so
+// memory stays bounded by the number of recently active keys. A key with callers blocked in Wait is
+// never evicted, and its idle time starts when the last of them returns. An evicted key starts again
+// with a full bucket and fresh counters, so the TTL should be at least the time it takes to refill a
+// bucket.
+type KeyedLimiter[K comparable] struct {
+	rate  Limit
+	burst int
+	ttl   time.Duration
+	opts  []Option
+	clock clock.Clock
+
+	mu      sync.Mutex
+	entries map[K]*keyedEntry
+
+	stopped chan struct{}
+	stop    sync.Once
+}
+
+type keyedEntry struct {
+	lim      *Limiter
+	lastSeen atomic.Int64
+	allowed  atomic.Uint64
+	denied   atomic.Uint64
+
+	// waiters counts the callers blocked in Wait, which may have taken a token the bucket doesn't
+	// have yet. It is guarded by the limiter's mutex.
+	waiters int
+}
+
+// NewKeyed returns a limiter that allows events at rate r with bursts of up to b events for each key,
+// evicting keys that have been idle for longer than ttl. A ttl of zero disables eviction. The options
+// apply to every per-key limiter.
+func NewKeyed[K comparable](r Limit, b int, ttl time.Duration, opts ...Option) *KeyedLimiter[K] {
+	k := &KeyedLimiter[K]{
+		rate:    r,
+		burst:   b,
+		ttl:     ttl,
+		opts:    opts,
+		clock:   newConfig(opts).clock,
+		entries: make(map[K]*keyedEntry),
+		stopped: make(chan struct{}),
+	}
+	if ttl > 0 {
+		go k.janitor()
+	}
+	return k
+}
+
+// janitor evicts idle keys every half TTL until the limiter is stopped.
+func (k *KeyedLimiter[K]) janitor() {
+	for {
+		t := k.clock.NewTimer(k.ttl / 2)
+		select {
+		case <-t.Chan():
+			k.Evict()
+		case <-k.stopped:
+			t.Stop()
+			return
+		}
+	}
+}
+
+// entry returns the bucket for key, creating it if needed, and marks it as used. It returns nil once
+// the limiter has been stopped.
+func (k *KeyedLimiter[K]) entry(key K) *keyedEntry {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.entryLocked(key)
+}
+
+func (k *KeyedLimiter[K]) entryLocked(key K) *keyedEntry {
+	if isClosed(k.stopped) {
+		return nil
+	}
+	e, ok := k.entries[key]
+	if !ok {
+		e = &keyedEntry{lim: New(k.rate, k.burst, k.opts...)}
+		k.entries[key] = e
+	}
+	e.lastSeen.Store(k.clock.Now().UnixNano())
+	return e
+}
+
+func (e *keyedEntry) record(ok bool) {
+	if ok {
+		e.allowed.Add(1)
+	} else {
+		e.denied.Add(1)
+	}
+}
+
+// Allow reports whether an event for key may happen now, spending one of its tokens if so.
+func (k *KeyedLimiter[K]) Allow(key K) bool {
+	e := k.entry(key)
+	if e == nil {
+		return false
+	}
+	ok := e.lim.Allow()
+	e.record(ok)
+	return ok
+}
+
+// Decide is like Allow but also reports the state of the key's bucket, as Limiter.Decide does.
+func (k *KeyedLimiter[K]) Decide(key K) Decision {
+	e := k.entry(key)
+	if e == nil {
+		return Decision{Limit: k.burst}
+	}
+	d := e.lim.Decide()
+	e.record(d.Allowed)
+	return d
+}
+
+// Wait blocks until an event for key may happen, as Limiter.Wait does. An event that fails to wait is
+// counted as denied.
+func (k *KeyedLimiter[K]) Wait(ctx context.Context, key K) error {
+	// Registering as a waiter under the same lock that finds the entry means Evict can't remove it in
+	// between, which would leave this caller spending tokens from a bucket nobody else sees.
+	k.mu.Lock()
+	e := k.entryLocked(key)
+	if e != nil {
+		e.waiters++
+	}
+	k.mu.Unlock()
+	if e == nil {
+		return ErrStopped
+	}
+
+	err := e.lim.Wait(ctx)
+	e.record(err == nil)
+
+	k.mu.Lock()
+	e.waiters--
+	e.lastSeen.Store(k.clock.Now().UnixNano())
+	k.mu.Unlock()
+	return err
+}
+
+// Stats returns the counters for key. The boolean result is false if the key is not currently tracked.
+func (k *KeyedLimiter[K]) Stats(key K) (KeyStats, bool) {
+	k.mu.Lock()
+	e, ok := k.entries[key]
+	k.mu.Unlock()
+	if !ok {
+		return KeyStats{}, false
+	}
+	return KeyStats{Allowed: e.allowed.Load(), Denied: e.denied.Load()}, true
+}
+
+// AllStats returns the counters of every key currently tracked.
+func (k *KeyedLimiter[K]) AllStats() map[K]KeyStats {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	stats := make(map[K]KeyStats, len(k.entries))
+	for key, e := range k.entries {
+		stats[key] = KeyStats{Allowed: e.allowed.Load(), Denied: e.denied.Load()}
+	}
+	return stats
+}
+
+// Len returns the number of keys currently tracked.
+func (k *KeyedLimiter[K]) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.entries)
+}
+
+// Evict removes the keys that have been idle for longer than the TTL and returns how many were
+// removed. Keys with callers blocked in Wait are kept. Evict runs periodically in the background, but
+// may also be called directly.
+func (k *KeyedLimiter[K]) Evict() int {
+	if k.ttl <= 0 {
+		return 0
+	}
+	cutoff := k.clock.Now().Add(-k.ttl).UnixNano()
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	n := 0
+	for key, e := range k.entries {
+		if e.waiters == 0 && e.lastSeen.Load() < cutoff {
+			delete(k.entries, key)
+			n++
+		}
+	}
+	return n
+}
+
+// Stop stops the eviction goroutine and every per-key limiter, waking callers blocked in Wait. All
+// later calls fail. It is safe to call Stop more than once.
+func (k *KeyedLimiter[K]) Stop() {
+	k.stop.Do(func() {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		close(k.stopped)
+		for _, e := range k.entries {
+			e.lim.Stop()
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+func assertStats(t *testing.T, l *KeyedLimiter[string], key string, want KeyStats) {
+	t.Helper()
+	got, ok := l.Stats(key)
+	if !ok {
+		t.Fatalf("Stats(%q) found no key", key)
+	}
+	if got != want {
+		t.Fatalf("Stats(%q) = %+v, want %+v", key, got, want)
+	}
+}
+
+func TestKeyedLimitsEachKeySeparately(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	l := NewKeyed[string](Every(time.Second), 2, 0, WithClock(fake))
+	defer l.Stop()
+
+	for i, want := range []bool{true, true, false} {
+		if got := l.Allow("a"); got != want {
+			t.Fatalf("Allow(a) #%d = %v, want %v", i+1, got, want)
+		}
+	}
+	if !l.Allow("b") {
+		t.Fatal("Allow(b) = false after a's bucket ran out, want true")
+	}
+	if d := l.Decide("a"); d.Allowed {
+		t.Fatalf("Decide(a) = %+v, want it denied", d)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx, "b"); err == nil {
+		t.Fatal("Wait with a cancelled context succeeded")
+	}
+
+	assertStats(t, l, "a", KeyStats{Allowed: 2, Denied: 2})
+	assertStats(t, l, "b", KeyStats{Allowed: 1, Denied: 1})
+	if _, ok := l.Stats("c"); ok {
+		t.Fatal("Stats(c) found a key that was never used")
+	}
+	want := map[string]KeyStats{"a": {Allowed: 2, Denied: 2}, "b": {Allowed: 1, Denied: 1}}
+	if got := l.AllStats(); !maps.Equal(got, want) {
+		t.Fatalf("AllStats = %v, want %v", got, want)
+	}
+	if n := l.Len(); n != 2 {
+		t.Fatalf("Len = %d, want 2", n)
+	}
+}
+
+func TestKeyedEvictsIdleKeys(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	l := NewKeyed[string](Every(time.Hour), 1, time.Minute, WithClock(fake))
+	defer l.Stop()
+
+	l.Allow("a")
+	l.Allow("a")
+	fake.Advance(30 * time.Second)
+	l.Allow("b")
+	fake.Advance(31 * time.Second)
+
+	// The janitor may have got there first, so only the result is checked, not Evict's count.
+	l.Evict()
+	if n := l.Len(); n != 1 {
+		t.Fatalf("Len = %d after a's TTL passed, want 1", n)
+	}
+	if _, ok := l.Stats("a"); ok {
+		t.Fatal("Stats(a) found the evicted key")
+	}
+	assertStats(t, l, "b", KeyStats{Allowed: 1})
+
+	// An evicted key starts again with a full bucket and fresh counters.
+	if !l.Allow("a") {
+		t.Fatal("Allow(a) = false after eviction, want a full bucket")
+	}
+	assertStats(t, l, "a", KeyStats{Allowed: 1})
+}
+
+func TestKeyedKeepsKeysWithWaiters(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	l := NewKeyed[string](Every(time.Minute), 1, 10*time.Second, WithClock(fake))
+	defer l.Stop()
+
+	l.Allow("a")
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Wait(context.Background(), "a")
+	}()
+	// One timer is the janitor's, the other the waiter's.
+	fake.BlockUntil(2)
+
+	fake.Advance(30 * time.Second)
+	l.Evict()
+	if n := l.Len(); n != 1 {
+		t.Fatal("a key with a caller blocked in Wait was evicted")
+	}
+	assertBlocked(t, done)
+
+	fake.Advance(30 * time.Second)
+	if err := receive(t, done); err != nil {
+		t.Fatalf("Wait = %v", err)
+	}
+	// The key counts as used when the wait ends, not when it began.
+	l.Evict()
+	if n := l.Len(); n != 1 {
+		t.Fatal("a key was evicted as soon as its wait ended")
+	}
+	assertStats(t, l, "a", KeyStats{Allowed: 2})
+
+	fake.Advance(11 * time.Second)
+	l.Evict()
+	if n := l.Len(); n != 0 {
+		t.Fatalf("Len = %d once the TTL passed after the wait, want 0", n)
+	}
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// KeyFunc derives the rate limiting key from a request.
+type KeyFunc func(r *http.Request) string
+
+// KeyByRemoteAddr uses the host part of the request's remote address as the key, so each client IP
+// gets its own bucket.
+func KeyByRemoteAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// KeyByHeader uses the value of the named header as the key, such as an API key or tenant ID.
+// Requests without the header fall back to KeyByRemoteAddr.
+func KeyByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		if v := r.Header.Get(name); v != "" {
+			return v
+		}
+		return KeyByRemoteAddr(r)
+	}
+}
+
+// Middleware returns HTTP middleware that limits requests per key, using key to derive the key from
+// each request; a nil key means KeyByRemoteAddr. Every response carries X-RateLimit-Limit,
+// X-RateLimit-Remaining and X-RateLimit-Reset headers, the last in seconds until the key's bucket is
+// full again. Throttled requests get a 429 Too Many Requests response with a Retry-After header and
+// never reach the wrapped handler.
+func Middleware(l *KeyedLimiter[string], key KeyFunc) func(http.Handler) http.Handler {
+	if key == nil {
+		key = KeyByRemoteAddr
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d := l.Decide(key(r))
+
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.Itoa(d.Limit))
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+			h.Set("X-RateLimit-Reset", seconds(d.Reset))
+			if !d.Allowed {
+				if d.RetryAfter > 0 {
+					h.Set("Retry-After", seconds(d.RetryAfter))
+				}
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// seconds formats d as a whole number of seconds, rounding up so clients don't retry too early.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
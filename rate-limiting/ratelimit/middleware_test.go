@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+// countingHandler counts the requests that get through the middleware.
+type countingHandler struct {
+	calls int
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls++
+	w.WriteHeader(http.StatusOK)
+}
+
+func serve(h http.Handler, remoteAddr string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = remoteAddr
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func assertHeaders(t *testing.T, w *httptest.ResponseRecorder, want map[string]string) {
+	t.Helper()
+	for name, v := range want {
+		if got := w.Header().Get(name); got != v {
+			t.Errorf("%s = %q, want %q", name, got, v)
+		}
+	}
+}
+
+func TestMiddlewareThrottles(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	l := NewKeyed[string](Every(time.Second), 2, 0, WithClock(fake))
+	defer l.Stop()
+	next := &countingHandler{}
+	h := Middleware(l, nil)(next)
+
+	w := serve(h, "192.0.2.1:1234", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("first request: status %d, want 200", w.Code)
+	}
+	assertHeaders(t, w, map[string]string{
+		"X-RateLimit-Limit":     "2",
+		"X-RateLimit-Remaining": "1",
+		"X-RateLimit-Reset":     "1",
+		"Retry-After":           "",
+	})
+
+	w = serve(h, "192.0.2.1:1234", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("second request: status %d, want 200", w.Code)
+	}
+	assertHeaders(t, w, map[string]string{
+		"X-RateLimit-Remaining": "0",
+		"X-RateLimit-Reset":     "2",
+	})
+
+	w = serve(h, "192.0.2.1:1234", nil)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("third request: status %d, want 429", w.Code)
+	}
+	assertHeaders(t, w, map[string]string{
+		"X-RateLimit-Limit":     "2",
+		"X-RateLimit-Remaining": "0",
+		"X-RateLimit-Reset":     "2",
+		"Retry-After":           "1",
+	})
+	if next.calls != 2 {
+		t.Fatalf("handler called %d times, want 2", next.calls)
+	}
+
+	// Another client has a bucket of its own.
+	if w := serve(h, "192.0.2.2:1234", nil); w.Code != http.StatusOK {
+		t.Fatalf("other client: status %d, want 200", w.Code)
+	}
+
+	// A second later the first client has a token again.
+	fake.Advance(time.Second)
+	if w := serve(h, "192.0.2.1:1234", nil); w.Code != http.StatusOK {
+		t.Fatalf("after refill: status %d, want 200", w.Code)
+	}
+	if next.calls != 4 {
+		t.Fatalf("handler called %d times, want 4", next.calls)
+	}
+}
+
+func TestMiddlewareKeyByHeader(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	l := NewKeyed[string](Every(time.Second), 1, 0, WithClock(fake))
+	defer l.Stop()
+	next := &countingHandler{}
+	h := Middleware(l, KeyByHeader("X-API-Key"))(next)
+
+	keyA := http.Header{"X-Api-Key": {"a"}}
+	keyB := http.Header{"X-Api-Key": {"b"}}
+
+	// Requests with the header are limited by its value, whatever address they come from.
+	if w := serve(h, "192.0.2.1:1234", keyA); w.Code != http.StatusOK {
+		t.Fatalf("key a: status %d, want 200", w.Code)
+	}
+	if w := serve(h, "192.0.2.2:1234", keyA); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("key a again: status %d, want 429", w.Code)
+	}
+	if w := serve(h, "192.0.2.1:1234", keyB); w.Code != http.StatusOK {
+		t.Fatalf("key b: status %d, want 200", w.Code)
+	}
+
+	// Requests without it fall back to the client address, which key a's requests didn't use up.
+	if w := serve(h, "192.0.2.1:1234", nil); w.Code != http.StatusOK {
+		t.Fatalf("no key: status %d, want 200", w.Code)
+	}
+	if w := serve(h, "192.0.2.1:5678", nil); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("no key, same host: status %d, want 429", w.Code)
+	}
+	if w := serve(h, "192.0.2.2:1234", nil); w.Code != http.StatusOK {
+		t.Fatalf("no key, other host: status %d, want 200", w.Code)
+	}
+	if next.calls != 4 {
+		t.Fatalf("handler called %d times, want 4", next.calls)
+	}
+}
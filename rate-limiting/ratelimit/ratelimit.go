@@ -0,0 +1,253 @@
+// Package ratelimit provides the rate limiters used by the rate-limiting example. Limiter is a token
+// bucket: tokens are added at a fixed rate up to a maximum burst, and every event spends one token.
+// SlidingLog, SlidingWindow and LeakyBucket offer other algorithms behind the same RateLimiter
+// interface.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+var (
+	// ErrStopped is returned once a limiter has been stopped.
+	ErrStopped = errors.New("ratelimit: limiter stopped")
+
+	// ErrUnavailable is returned when no token will ever become available, because the rate is zero
+	// or the burst is less than one.
+	ErrUnavailable = errors.New("ratelimit: no tokens will become available")
+)
+
+// Limit is a rate of events per second.
+type Limit float64
+
+// Inf is the infinite rate limit; it allows all events, even if the burst is zero.
+const Inf = Limit(math.MaxFloat64)
+
+// Every converts a minimum time interval between events to a Limit.
+func Every(interval time.Duration) Limit {
+	if interval <= 0 {
+		return Inf
+	}
+	return 1 / Limit(interval.Seconds())
+}
+
+// durationFor returns how long it takes to accumulate the given number of tokens at rate r.
+func (r Limit) durationFor(tokens float64) time.Duration {
+	if r <= 0 {
+		return math.MaxInt64
+	}
+	return time.Duration(tokens / float64(r) * float64(time.Second))
+}
+
+// tokensFor returns how many tokens accumulate in d at rate r.
+func (r Limit) tokensFor(d time.Duration) float64 {
+	if r <= 0 {
+		return 0
+	}
+	return d.Seconds() * float64(r)
+}
+
+// Limiter is a token bucket rate limiter. It holds no goroutines or timers of its own: the bucket is
+// refilled lazily whenever it is used. It is safe for concurrent use.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   Limit
+	burst  int
+	tokens float64
+	last   time.Time
+
+	clock   clock.Clock
+	stopped chan struct{}
+	stop    sync.Once
+}
+
+// New returns a limiter that allows events at rate r with bursts of up to b events. The bucket starts
+// full.
+func New(r Limit, b int, opts ...Option) *Limiter {
+	c := newConfig(opts)
+	return &Limiter{
+		rate:    r,
+		burst:   b,
+		tokens:  float64(b),
+		last:    c.clock.Now(),
+		clock:   c.clock,
+		stopped: make(chan struct{}),
+	}
+}
+
+// advanceLocked refills the bucket for the time elapsed since it was last used.
+func (l *Limiter) advanceLocked(now time.Time) {
+	if now.After(l.last) {
+		l.tokens = math.Min(float64(l.burst), l.tokens+l.rate.tokensFor(now.Sub(l.last)))
+		l.last = now
+	}
+}
+
+// reserve takes a token, returning how long the caller must wait before the event may happen. If
+// maxWait is non-negative and the wait would be longer, nothing is reserved and ok is false.
+func (l *Limiter) reserve(now time.Time, maxWait time.Duration) (delay time.Duration, ok bool, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.reserveLocked(now, maxWait)
+}
+
+func (l *Limiter) reserveLocked(now time.Time, maxWait time.Duration) (delay time.Duration, ok bool, err error) {
+	if isClosed(l.stopped) {
+		return 0, false, ErrStopped
+	}
+	if l.rate == Inf {
+		return 0, true, nil
+	}
+
+	l.advanceLocked(now)
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0, true, nil
+	}
+	if l.rate <= 0 || l.burst < 1 {
+		return 0, false, ErrUnavailable
+	}
+
+	delay = l.rate.durationFor(1 - l.tokens)
+	if maxWait >= 0 && delay > maxWait {
+		return delay, false, nil
+	}
+	l.tokens--
+	return delay, true, nil
+}
+
+// cancel returns a token taken by reserve for an event that will no longer happen.
+func (l *Limiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = math.Min(float64(l.burst), l.tokens+1)
+}
+
+// Allow reports whether an event may happen now, spending a token if so.
+func (l *Limiter) Allow() bool {
+	_, ok, err := l.reserve(l.clock.Now(), 0)
+	return ok && err == nil
+}
+
+// Decision describes the outcome of asking a limiter whether an event may happen now. Limit is the
+// burst, Remaining the number of whole tokens left afterwards and Reset how long until the bucket is
+// full again. RetryAfter is set when the event was denied and says how long until a token will be
+// available; it is zero if none ever will be.
+type Decision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+// Decide is like Allow but also reports the state of the bucket, such as for rate limit headers.
+func (l *Limiter) Decide() Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delay, ok, err := l.reserveLocked(l.clock.Now(), 0)
+
+	d := Decision{Allowed: ok && err == nil, Limit: l.burst}
+	if l.rate == Inf {
+		d.Remaining = l.burst
+		return d
+	}
+	d.Remaining = max(int(l.tokens), 0)
+	if !d.Allowed && err == nil {
+		d.RetryAfter = delay
+	}
+	if missing := float64(l.burst) - l.tokens; missing > 0 {
+		d.Reset = l.rate.durationFor(missing)
+	}
+	return d
+}
+
+// Reserve takes a token and returns how long the caller must wait before the event may happen. The
+// token is spent even if the caller decides not to wait.
+func (l *Limiter) Reserve() (time.Duration, error) {
+	delay, _, err := l.reserve(l.clock.Now(), -1)
+	return delay, err
+}
+
+// Wait blocks until an event may happen. It returns an error if ctx is done or the limiter is stopped
+// first, in which case the token is given back. If ctx has a deadline that would pass before a token
+// is available, Wait returns context.DeadlineExceeded straight away.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := l.clock.Now()
+	maxWait := time.Duration(-1)
+	if deadline, ok := ctx.Deadline(); ok {
+		maxWait = deadline.Sub(now)
+	}
+	delay, ok, err := l.reserve(now, maxWait)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return context.DeadlineExceeded
+	}
+	if delay == 0 {
+		return nil
+	}
+
+	t := l.clock.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.Chan():
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	case <-l.stopped:
+		return ErrStopped
+	}
+}
+
+// SetRate changes the rate at which tokens are added. Tokens accumulated so far are kept.
+func (l *Limiter) SetRate(r Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advanceLocked(l.clock.Now())
+	l.rate = r
+}
+
+// SetBurst changes the maximum burst. If the bucket holds more tokens than the new burst, the excess
+// is discarded.
+func (l *Limiter) SetBurst(b int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advanceLocked(l.clock.Now())
+	l.burst = b
+	l.tokens = math.Min(float64(b), l.tokens)
+}
+
+// Rate returns the current rate.
+func (l *Limiter) Rate() Limit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// Burst returns the current maximum burst.
+func (l *Limiter) Burst() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.burst
+}
+
+// Stop stops the limiter. Callers blocked in Wait return ErrStopped and release their timers, and all
+// later calls fail. It is safe to call Stop more than once.
+func (l *Limiter) Stop() {
+	l.stop.Do(func() {
+		close(l.stopped)
+	})
+}
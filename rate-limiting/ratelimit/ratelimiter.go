@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+// RateLimiter is implemented by every limiter in this package, so callers can swap algorithms.
+type RateLimiter interface {
+	// Allow reports whether an event may happen now, recording it if so.
+	Allow() bool
+	// Wait blocks until an event may happen, or returns an error if ctx is done or the limiter is
+	// stopped first.
+	Wait(ctx context.Context) error
+	// Stop releases the limiter's resources and wakes any callers blocked in Wait.
+	Stop()
+}
+
+var (
+	_ RateLimiter = (*Limiter)(nil)
+	_ RateLimiter = (*SlidingLog)(nil)
+	_ RateLimiter = (*SlidingWindow)(nil)
+	_ RateLimiter = (*LeakyBucket)(nil)
+)
+
+// Option configures a limiter.
+type Option func(*config)
+
+type config struct {
+	clock clock.Clock
+}
+
+func newConfig(opts []Option) config {
+	c := config{clock: clock.Real()}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// WithClock sets the clock a limiter reads the time from and waits on. The default is the system
+// clock.
+func WithClock(clk clock.Clock) Option {
+	return func(c *config) {
+		c.clock = clk
+	}
+}
+
+// isClosed reports whether the channel c has been closed, without blocking.
+func isClosed(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleep waits for d on clk, returning early with an error if ctx is done or stopped is closed.
+func sleep(ctx context.Context, clk clock.Clock, d time.Duration, stopped <-chan struct{}) error {
+	t := clk.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.Chan():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-stopped:
+		return ErrStopped
+	}
+}
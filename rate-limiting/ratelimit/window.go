@@ -0,0 +1,331 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+// SlidingLog allows at most limit events in any rolling window, exactly. It records the time of
+// every event in the last window, so its memory grows with the limit.
+type SlidingLog struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	log    []time.Time
+
+	clock   clock.Clock
+	stopped chan struct{}
+	stop    sync.Once
+}
+
+// NewSlidingLog returns a limiter that allows at most limit events in any rolling window. It panics if
+// window is not positive.
+func NewSlidingLog(limit int, window time.Duration, opts ...Option) *SlidingLog {
+	if window <= 0 {
+		panic("ratelimit: non-positive window")
+	}
+	c := newConfig(opts)
+	return &SlidingLog{
+		limit:   limit,
+		window:  window,
+		clock:   c.clock,
+		stopped: make(chan struct{}),
+	}
+}
+
+// reserveLocked records an event at the earliest time no sooner than now at which it fits within the
+// limit, and returns that time. Times in the log may be in the future for callers still in Wait.
+func (l *SlidingLog) reserveLocked(now time.Time) time.Time {
+	cutoff := now.Add(-l.window)
+	i := 0
+	for i < len(l.log) && !l.log[i].After(cutoff) {
+		i++
+	}
+	l.log = slices.Delete(l.log, 0, i)
+
+	at := now
+	if len(l.log) >= l.limit {
+		if next := l.log[len(l.log)-l.limit].Add(l.window); next.After(at) {
+			at = next
+		}
+	}
+	l.log = append(l.log, at)
+	return at
+}
+
+// Allow reports whether an event may happen now, recording it if so.
+func (l *SlidingLog) Allow() bool {
+	if isClosed(l.stopped) || l.limit < 1 {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	if at := l.reserveLocked(now); at.After(now) {
+		l.log = l.log[:len(l.log)-1]
+		return false
+	}
+	return true
+}
+
+// Wait blocks until an event may happen. If ctx is done or the limiter is stopped first, the event is
+// removed from the log again.
+func (l *SlidingLog) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if isClosed(l.stopped) {
+		return ErrStopped
+	}
+	if l.limit < 1 {
+		return ErrUnavailable
+	}
+
+	l.mu.Lock()
+	now := l.clock.Now()
+	at := l.reserveLocked(now)
+	l.mu.Unlock()
+	if !at.After(now) {
+		return nil
+	}
+
+	err := sleep(ctx, l.clock, at.Sub(now), l.stopped)
+	if err != nil {
+		l.mu.Lock()
+		if i := slices.Index(l.log, at); i >= 0 {
+			l.log = slices.Delete(l.log, i, i+1)
+		}
+		l.mu.Unlock()
+	}
+	return err
+}
+
+// Stop stops the limiter, waking callers blocked in Wait. It is safe to call Stop more than once.
+func (l *SlidingLog) Stop() {
+	l.stop.Do(func() {
+		close(l.stopped)
+	})
+}
+
+// SlidingWindow approximates a rolling window using counters for the current and previous fixed
+// windows, weighting the previous count by how much of it still overlaps the rolling window. It uses
+// constant memory regardless of the limit, at the cost of assuming events in the previous window were
+// evenly spread.
+type SlidingWindow struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	start  time.Time
+	prev   int
+	curr   int
+
+	clock   clock.Clock
+	stopped chan struct{}
+	stop    sync.Once
+}
+
+// NewSlidingWindow returns a limiter that allows approximately limit events in any rolling window. It
+// panics if window is not positive.
+func NewSlidingWindow(limit int, window time.Duration, opts ...Option) *SlidingWindow {
+	if window <= 0 {
+		panic("ratelimit: non-positive window")
+	}
+	c := newConfig(opts)
+	return &SlidingWindow{
+		limit:   limit,
+		window:  window,
+		start:   c.clock.Now(),
+		clock:   c.clock,
+		stopped: make(chan struct{}),
+	}
+}
+
+// advanceLocked moves the fixed windows forward so that now falls in the current one.
+func (l *SlidingWindow) advanceLocked(now time.Time) {
+	elapsed := now.Sub(l.start)
+	if elapsed < l.window {
+		return
+	}
+	n := elapsed / l.window
+	if n == 1 {
+		l.prev = l.curr
+	} else {
+		l.prev = 0
+	}
+	l.curr = 0
+	l.start = l.start.Add(n * l.window)
+}
+
+// tryLocked records an event if it fits within the limit now. Otherwise it returns how long to wait
+// before trying again.
+func (l *SlidingWindow) tryLocked(now time.Time) (bool, time.Duration) {
+	l.advanceLocked(now)
+	elapsed := now.Sub(l.start)
+	weight := 1 - float64(elapsed)/float64(l.window)
+	if float64(l.prev)*weight+float64(l.curr)+1 <= float64(l.limit) {
+		l.curr++
+		return true, 0
+	}
+
+	// The weighted previous count shrinks as the window slides. If the current count alone is at
+	// the limit we have to wait for the next window, where it becomes the previous count instead.
+	free := float64(l.limit - l.curr - 1)
+	if free >= 0 && l.prev > 0 {
+		at := time.Duration(float64(l.window) * (1 - free/float64(l.prev)))
+		return false, max(at-elapsed, 1)
+	}
+	next := l.window - elapsed
+	at := time.Duration(float64(l.window) * (1 - float64(l.limit-1)/float64(l.curr)))
+	return false, max(next+at, 1)
+}
+
+// Allow reports whether an event may happen now, recording it if so.
+func (l *SlidingWindow) Allow() bool {
+	if isClosed(l.stopped) || l.limit < 1 {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ok, _ := l.tryLocked(l.clock.Now())
+	return ok
+}
+
+// Wait blocks until an event may happen. Waiting callers are not queued, so when several are blocked
+// at once they race for the next free slot.
+func (l *SlidingWindow) Wait(ctx context.Context) error {
+	if l.limit < 1 {
+		return ErrUnavailable
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if isClosed(l.stopped) {
+			return ErrStopped
+		}
+
+		l.mu.Lock()
+		ok, retry := l.tryLocked(l.clock.Now())
+		l.mu.Unlock()
+		if ok {
+			return nil
+		}
+		if err := sleep(ctx, l.clock, retry, l.stopped); err != nil {
+			return err
+		}
+	}
+}
+
+// Stop stops the limiter, waking callers blocked in Wait. It is safe to call Stop more than once.
+func (l *SlidingWindow) Stop() {
+	l.stop.Do(func() {
+		close(l.stopped)
+	})
+}
+
+// LeakyBucket smooths events to a constant rate: each event is scheduled one interval after the one
+// before it, so unlike Limiter it never lets a burst through at once. The bucket holds at most
+// capacity events waiting for their turn; Wait fails with ErrUnavailable while it is full.
+type LeakyBucket struct {
+	mu       sync.Mutex
+	interval time.Duration
+	capacity int
+	next     time.Time
+
+	clock   clock.Clock
+	stopped chan struct{}
+	stop    sync.Once
+}
+
+// never is the interval of a leaky bucket whose rate is zero.
+const never = time.Duration(math.MaxInt64)
+
+// NewLeakyBucket returns a limiter that lets events through at rate r, with room for up to capacity
+// events waiting.
+func NewLeakyBucket(r Limit, capacity int, opts ...Option) *LeakyBucket {
+	c := newConfig(opts)
+	interval := time.Duration(0)
+	if r != Inf {
+		interval = r.durationFor(1)
+	}
+	return &LeakyBucket{
+		interval: interval,
+		capacity: capacity,
+		next:     c.clock.Now(),
+		clock:    c.clock,
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Allow reports whether an event may happen now, which is only the case when no other event is
+// waiting for its turn.
+func (l *LeakyBucket) Allow() bool {
+	if isClosed(l.stopped) || l.interval == never {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	if l.next.After(now) {
+		return false
+	}
+	l.next = now.Add(l.interval)
+	return true
+}
+
+// Wait blocks until it is this event's turn to leak out of the bucket. If ctx is done or the limiter
+// is stopped first, the turn is given back if no later event has been scheduled since.
+func (l *LeakyBucket) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if isClosed(l.stopped) {
+		return ErrStopped
+	}
+	if l.interval == never {
+		return ErrUnavailable
+	}
+
+	l.mu.Lock()
+	now := l.clock.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	// Events are spaced one interval apart, so the delay tells us our place in the queue.
+	delay := at.Sub(now)
+	if delay > 0 && int(math.Ceil(float64(delay)/float64(l.interval))) > l.capacity {
+		l.mu.Unlock()
+		return ErrUnavailable
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	err := sleep(ctx, l.clock, delay, l.stopped)
+	if err != nil {
+		l.mu.Lock()
+		if l.next.Equal(at.Add(l.interval)) {
+			l.next = at
+		}
+		l.mu.Unlock()
+	}
+	return err
+}
+
+// Stop stops the limiter, waking callers blocked in Wait. It is safe to call Stop more than once.
+func (l *LeakyBucket) Stop() {
+	l.stop.Do(func() {
+		close(l.stopped)
+	})
+}
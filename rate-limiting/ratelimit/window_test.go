@@ -0,0 +1,252 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+// waitAsync calls l.Wait on a new goroutine and returns a channel that receives its result.
+func waitAsync(ctx context.Context, l RateLimiter) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Wait(ctx)
+	}()
+	return done
+}
+
+// assertBlocked fails the test if done has a result. Fake time is only moved by the test, so a short
+// real-time wait is enough to catch a Wait that returned when it shouldn't have.
+func assertBlocked(t *testing.T, done <-chan error) {
+	t.Helper()
+	select {
+	case err := <-done:
+		t.Fatalf("Wait returned %v, want it still blocked", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func receive(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not return")
+		return nil
+	}
+}
+
+func assertAllows(t *testing.T, l RateLimiter, want ...bool) {
+	t.Helper()
+	for i, w := range want {
+		if got := l.Allow(); got != w {
+			t.Fatalf("Allow #%d = %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func assertPanics(t *testing.T, f func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Fatal("did not panic")
+		}
+	}()
+	f()
+}
+
+func TestSlidingLogAllow(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	l := NewSlidingLog(3, time.Second, WithClock(fake))
+	defer l.Stop()
+
+	assertAllows(t, l, true, true, true, false)
+	fake.Advance(999 * time.Millisecond)
+	assertAllows(t, l, false)
+	// The first three events leave the window together, a full window after they happened.
+	fake.Advance(time.Millisecond)
+	assertAllows(t, l, true, true, true, false)
+}
+
+func TestSlidingLogWaitWakesWhenEventLeavesWindow(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	l := NewSlidingLog(2, time.Second, WithClock(fake))
+	defer l.Stop()
+
+	assertAllows(t, l, true)
+	fake.Advance(500 * time.Millisecond)
+	assertAllows(t, l, true)
+
+	done := waitAsync(context.Background(), l)
+	fake.BlockUntil(1)
+	fake.Advance(499 * time.Millisecond)
+	assertBlocked(t, done)
+	fake.Advance(time.Millisecond)
+	if err := receive(t, done); err != nil {
+		t.Fatalf("Wait = %v", err)
+	}
+}
+
+func TestSlidingLogWaitCancelRollsBack(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	l := NewSlidingLog(2, time.Second, WithClock(fake))
+	defer l.Stop()
+	assertAllows(t, l, true, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := waitAsync(ctx, l)
+	fake.BlockUntil(1)
+	cancel()
+	if err := receive(t, done); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait = %v, want context.Canceled", err)
+	}
+
+	// Had the cancelled Wait kept its slot, only one event would fit once the window has passed.
+	fake.Advance(time.Second)
+	assertAllows(t, l, true, true, false)
+}
+
+func TestSlidingLogStopWakesWait(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	l := NewSlidingLog(1, time.Second, WithClock(fake))
+	assertAllows(t, l, true)
+
+	done := waitAsync(context.Background(), l)
+	fake.BlockUntil(1)
+	l.Stop()
+	if err := receive(t, done); !errors.Is(err, ErrStopped) {
+		t.Fatalf("Wait = %v, want ErrStopped", err)
+	}
+	assertAllows(t, l, false)
+}
+
+func TestSlidingWindowWeightsPreviousWindow(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	l := NewSlidingWindow(4, time.Second, WithClock(fake))
+	defer l.Stop()
+
+	assertAllows(t, l, true, true, true, true, false)
+
+	// At the start of the next window all four previous events still count.
+	fake.Advance(time.Second)
+	assertAllows(t, l, false)
+	// A quarter of the way in, they count as three, leaving room for one more.
+	fake.Advance(250 * time.Millisecond)
+	assertAllows(t, l, true, false)
+	// Half way, they count as two, and with the one just allowed there is room for one more.
+	fake.Advance(250 * time.Millisecond)
+	assertAllows(t, l, true, false)
+}
+
+func TestSlidingWindowForgetsOlderWindows(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	l := NewSlidingWindow(2, time.Second, WithClock(fake))
+	defer l.Stop()
+
+	assertAllows(t, l, true, true, false)
+	fake.Advance(2 * time.Second)
+	assertAllows(t, l, true, true, false)
+}
+
+func TestSlidingWindowWaitWakesWhenWeightAllows(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	l := NewSlidingWindow(1, time.Second, WithClock(fake))
+	defer l.Stop()
+	assertAllows(t, l, true)
+
+	// The event stays fully in the current window for a second, then its weight falls to zero over
+	// the next one.
+	done := waitAsync(context.Background(), l)
+	fake.BlockUntil(1)
+	fake.Advance(2*time.Second - time.Millisecond)
+	assertBlocked(t, done)
+	fake.Advance(time.Millisecond)
+	if err := receive(t, done); err != nil {
+		t.Fatalf("Wait = %v", err)
+	}
+	assertAllows(t, l, false)
+}
+
+func TestSlidingWindowWaitCancelRecordsNothing(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	l := NewSlidingWindow(1, time.Second, WithClock(fake))
+	defer l.Stop()
+	assertAllows(t, l, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := waitAsync(ctx, l)
+	fake.BlockUntil(1)
+	cancel()
+	if err := receive(t, done); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait = %v, want context.Canceled", err)
+	}
+
+	fake.Advance(2 * time.Second)
+	assertAllows(t, l, true, false)
+}
+
+func TestSlidingWindowRejectsNonPositiveWindow(t *testing.T) {
+	assertPanics(t, func() { NewSlidingWindow(1, 0) })
+	assertPanics(t, func() { NewSlidingLog(1, -time.Second) })
+}
+
+func TestLeakyBucketAllow(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	l := NewLeakyBucket(Every(100*time.Millisecond), 2, WithClock(fake))
+	defer l.Stop()
+
+	assertAllows(t, l, true, false)
+	fake.Advance(99 * time.Millisecond)
+	assertAllows(t, l, false)
+	fake.Advance(time.Millisecond)
+	assertAllows(t, l, true, false)
+}
+
+func TestLeakyBucketWaitSpacesEvents(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	l := NewLeakyBucket(Every(100*time.Millisecond), 2, WithClock(fake))
+	defer l.Stop()
+	assertAllows(t, l, true)
+
+	// Two events fit in the bucket, 100ms and 200ms from now; a third would overflow it.
+	first := waitAsync(context.Background(), l)
+	fake.BlockUntil(1)
+	second := waitAsync(context.Background(), l)
+	fake.BlockUntil(2)
+	if err := l.Wait(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Wait on a full bucket = %v, want ErrUnavailable", err)
+	}
+
+	fake.Advance(100 * time.Millisecond)
+	if err := receive(t, first); err != nil {
+		t.Fatalf("first Wait = %v", err)
+	}
+	assertBlocked(t, second)
+	fake.Advance(100 * time.Millisecond)
+	if err := receive(t, second); err != nil {
+		t.Fatalf("second Wait = %v", err)
+	}
+}
+
+func TestLeakyBucketWaitCancelGivesTurnBack(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	l := NewLeakyBucket(Every(100*time.Millisecond), 2, WithClock(fake))
+	defer l.Stop()
+	assertAllows(t, l, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := waitAsync(ctx, l)
+	fake.BlockUntil(1)
+	cancel()
+	if err := receive(t, done); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait = %v, want context.Canceled", err)
+	}
+
+	// The cancelled event's turn, 100ms from the start, is free again.
+	fake.Advance(100 * time.Millisecond)
+	assertAllows(t, l, true)
+}
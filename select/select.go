@@ -3,9 +3,15 @@ package main
 import (
 	"fmt"
 	"time"
+
+	"github.com/yanw2/go-by-example/clock"
 )
 
 func main() {
+	run(clock.Real())
+}
+
+func run(clk clock.Clock) {
 	// Go's `select` lets you wait on multiple channel operations.
 	// For our example, we'll select across two channels. Each channel will receive a value after
 	// some amount of time.
@@ -13,12 +19,12 @@ func main() {
 	c2 := make(chan string)
 
 	go func() {
-		time.Sleep(1 * time.Second)
+		clk.Sleep(1 * time.Second)
 		c1 <- "one"
 	}()
 
 	go func() {
-		time.Sleep(2 * time.Second)
+		clk.Sleep(2 * time.Second)
 		c2 <- "two"
 	}()
 
@@ -38,7 +44,7 @@ func main() {
 	// nonblocking. This is a common pattern to prevent goroutine leaks in case the channel is never read.
 	c3 := make(chan string, 1)
 	go func() {
-		time.Sleep(2 * time.Second)
+		clk.Sleep(2 * time.Second)
 		c3 <- "result 3"
 	}()
 
@@ -47,21 +53,21 @@ func main() {
 	select {
 	case res := <-c3:
 		fmt.Println(res)
-	case <-time.After(1 * time.Second):
+	case <-clk.After(1 * time.Second):
 		fmt.Println("timeout 1")
 	}
 
 	// If we allow a longer timeout of 3s, then the receive from c4 will success and we'll print the result
 	c4 := make(chan string, 1)
 	go func() {
-		time.Sleep(2 * time.Second)
+		clk.Sleep(2 * time.Second)
 		c4 <- "result 4"
 	}()
 
 	select {
 	case res := <-c4:
 		fmt.Println(res)
-	case <-time.After(3 * time.Second):
+	case <-clk.After(3 * time.Second):
 		fmt.Println("timeout 2")
 	}
 
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yanw2/go-by-example/mutexes/shardmap"
+	"github.com/yanw2/go-by-example/stateful-goroutines/actor"
+)
+
+// The atomic-counters, mutexes and stateful-goroutines examples show three ways to manage shared
+// state, but only print how many operations they got through in a second. The benchmarks in
+// `state-strategies_test.go` compare them side by side with the `testing` package, under a range of
+// goroutine counts and read/write mixes, so a strategy can be picked with data. Run them with
+// `go test -bench . ./state-strategies`.
+//
+// Each strategy implements `state`: a fixed set of integer keys, each holding an integer.
+type state interface {
+	load(key int) int
+	store(key, val int)
+}
+
+// The mutexes example: a map behind a single `sync.Mutex`.
+type mutexState struct {
+	mu sync.Mutex
+	m  map[int]int
+}
+
+func (s *mutexState) load(key int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.m[key]
+}
+
+func (s *mutexState) store(key, val int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[key] = val
+}
+
+// The same map behind a `sync.RWMutex`, so that readers don't wait for each other.
+type rwMutexState struct {
+	mu sync.RWMutex
+	m  map[int]int
+}
+
+func (s *rwMutexState) load(key int) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m[key]
+}
+
+func (s *rwMutexState) store(key, val int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[key] = val
+}
+
+// The map from the `shardmap` package, with a `sync.RWMutex` per shard.
+type shardedState struct {
+	m *shardmap.Map[int, int]
+}
+
+func (s shardedState) load(key int) int {
+	v, _ := s.m.Load(key)
+	return v
+}
+
+func (s shardedState) store(key, val int) {
+	s.m.Store(key, val)
+}
+
+// The atomic-counters approach: since the keys are a fixed range of integers, each one can be its own
+// `atomic.Int64` and no lock is needed at all.
+type atomicState []atomic.Int64
+
+func (s atomicState) load(key int) int {
+	return int(s[key].Load())
+}
+
+func (s atomicState) store(key, val int) {
+	s[key].Store(int64(val))
+}
+
+// The stateful-goroutines approach: the map is owned by the goroutine of an `actor.Store`.
+type actorState struct {
+	s *actor.Store[int, int]
+}
+
+func (s actorState) load(key int) int {
+	v, _ := s.s.Get(context.Background(), key)
+	return v
+}
+
+func (s actorState) store(key, val int) {
+	s.s.Set(context.Background(), key, val)
+}
+
+type strategy struct {
+	name  string
+	new   func(keys int) state
+	close func(state)
+}
+
+var strategies = []strategy{
+	{name: "mutex", new: func(int) state { return &mutexState{m: make(map[int]int)} }},
+	{name: "rwmutex", new: func(int) state { return &rwMutexState{m: make(map[int]int)} }},
+	{name: "sharded", new: func(int) state { return shardedState{shardmap.New[int, int]()} }},
+	{name: "atomic", new: func(keys int) state { return make(atomicState, keys) }},
+	{
+		name: "actor",
+		new:  func(int) state { return actorState{actor.NewStore[int, int]()} },
+		close: func(s state) {
+			s.(actorState).s.Stop(context.Background())
+		},
+	},
+}
+
+// run performs ops operations against s, split evenly between the given number of goroutines.
+// writes is the percentage of operations that are writes; the rest are reads. Keys are picked at
+// random.
+func run(s state, ops, goroutines, writes, keys int) {
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		n := ops / goroutines
+		if g < ops%goroutines {
+			n++
+		}
+		wg.Add(1)
+		go func(seed uint64) {
+			defer wg.Done()
+			// A random source per goroutine, so the goroutines don't contend on the global one.
+			r := rand.New(rand.NewPCG(seed, seed))
+			for i := 0; i < n; i++ {
+				key := r.IntN(keys)
+				if r.IntN(100) < writes {
+					s.store(key, i)
+				} else {
+					s.load(key)
+				}
+			}
+		}(uint64(g))
+	}
+	wg.Wait()
+}
+
+// For a first impression we time a million operations with each strategy, using the mix from the
+// earlier examples: 110 goroutines, 10 of them writers, over 5 keys. The benchmarks give steadier
+// numbers, along with allocations, and can be compared across runs with benchstat.
+func main() {
+	for _, st := range strategies {
+		s := st.new(5)
+		start := time.Now()
+		run(s, 1000000, 110, 9, 5)
+		fmt.Printf("%-8s %v\n", st.name, time.Since(start).Round(time.Millisecond))
+		if st.close != nil {
+			st.close(s)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The grid the benchmarks run over can be changed with flags, for example
+//
+//	go test -bench . ./state-strategies -goroutines 1,8,64 -writes 0,10,90 -keys 1000
+//
+// and a single strategy picked with -bench, as in -bench 'Strategies/actor/'. The defaults follow the
+// earlier examples: 110 goroutines, 10 of them writers, over 5 keys.
+var (
+	goroutinesFlag = flag.String("goroutines", "1,10,110", "comma-separated goroutine counts")
+	writesFlag     = flag.String("writes", "9,50", "comma-separated percentages of operations that are writes")
+	keysFlag       = flag.Int("keys", 5, "number of keys")
+)
+
+// BenchmarkStrategies runs every strategy for each combination of goroutine count and write
+// percentage, as sub-benchmarks named strategy/goroutines=N/writes=P.
+//
+// On a typical run the atomics are fastest by a wide margin, the lock-based maps are close to each
+// other with few CPUs and pull apart with more, and the actor is the slowest: every operation is a
+// round trip between goroutines and allocates its command and reply. The actor's strength is not
+// speed but that arbitrary operations on the state run one at a time.
+func BenchmarkStrategies(b *testing.B) {
+	goroutines, err := parseInts(*goroutinesFlag, 1, 1<<20)
+	if err != nil {
+		b.Fatal("-goroutines:", err)
+	}
+	writes, err := parseInts(*writesFlag, 0, 100)
+	if err != nil {
+		b.Fatal("-writes:", err)
+	}
+	keys := *keysFlag
+	if keys < 1 {
+		b.Fatal("-keys: must be at least 1")
+	}
+
+	for _, st := range strategies {
+		b.Run(st.name, func(b *testing.B) {
+			for _, g := range goroutines {
+				b.Run(fmt.Sprintf("goroutines=%d", g), func(b *testing.B) {
+					for _, wr := range writes {
+						b.Run(fmt.Sprintf("writes=%d", wr), func(b *testing.B) {
+							s := st.new(keys)
+							if st.close != nil {
+								defer st.close(s)
+							}
+							b.ReportAllocs()
+							b.ResetTimer()
+							run(s, b.N, g, wr, keys)
+						})
+					}
+				})
+			}
+		})
+	}
+}
+
+func parseInts(s string, lo, hi int) ([]int, error) {
+	var ns []int
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return nil, err
+		}
+		if n < lo || n > hi {
+			return nil, fmt.Errorf("%d out of range [%d, %d]", n, lo, hi)
+		}
+		ns = append(ns, n)
+	}
+	return ns, nil
+}
@@ -0,0 +1,123 @@
+// Package actor generalizes the stateful-goroutines example. An Actor owns a value of any type on its
+// own goroutine, and other goroutines change or read it by sending commands to that goroutine rather
+// than by sharing memory. Unlike the example's owner goroutine, an actor can be stopped, callers can
+// give up on a command through its context, and commands report errors back through their reply.
+package actor
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/yanw2/go-by-example/panic/supervise"
+)
+
+var (
+	// ErrStopped is returned for commands sent to an actor that has been stopped.
+	ErrStopped = errors.New("actor: stopped")
+
+	// ErrNotFound is returned by Store.Get for a key that isn't stored.
+	ErrNotFound = errors.New("actor: key not found")
+)
+
+// command is a request for the owner goroutine, like the example's readOp and writeOp. The reply
+// channel is buffered so the actor never blocks on a caller that has given up waiting.
+type command[S any] struct {
+	ctx   context.Context
+	fn    func(*S) error
+	reply chan error
+}
+
+// Actor owns a value of type S. It is safe for concurrent use, and commands run one at a time in the
+// order the actor receives them.
+type Actor[S any] struct {
+	commands chan command[S]
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// New starts an actor that owns state.
+func New[S any](state S) *Actor[S] {
+	a := &Actor[S]{
+		commands: make(chan command[S]),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go a.loop(state)
+	return a
+}
+
+func (a *Actor[S]) loop(state S) {
+	defer close(a.done)
+	for {
+		select {
+		case cmd := <-a.commands:
+			// The caller may have given up between sending the command and us receiving it.
+			if err := cmd.ctx.Err(); err != nil {
+				cmd.reply <- err
+				continue
+			}
+			// A panicking command would otherwise take the actor, and the program, down with it.
+			cmd.reply <- supervise.Catch(func() error {
+				return cmd.fn(&state)
+			})
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+// Do runs fn on the actor's goroutine with a pointer to the state, and returns fn's error, or a
+// *supervise.PanicError if fn panics. fn must not keep the pointer, or anything reachable through it,
+// after it returns, and must not send commands to the same actor, which would deadlock.
+//
+// If ctx is done before fn starts, Do returns ctx.Err() and fn never runs. If ctx is done while fn is
+// running, Do returns ctx.Err() straight away, but fn still runs to completion.
+func (a *Actor[S]) Do(ctx context.Context, fn func(state *S) error) error {
+	cmd := command[S]{ctx: ctx, fn: fn, reply: make(chan error, 1)}
+	select {
+	case a.commands <- cmd:
+	case <-a.done:
+		return ErrStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-cmd.reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Ask runs fn on the actor's goroutine and returns its result, like Do for commands that read
+// something back. It is a function rather than a method because methods can't have type parameters.
+func Ask[S, R any](ctx context.Context, a *Actor[S], fn func(state *S) (R, error)) (R, error) {
+	var result R
+	err := a.Do(ctx, func(s *S) error {
+		var err error
+		result, err = fn(s)
+		return err
+	})
+	if err != nil {
+		var zero R
+		return zero, err
+	}
+	return result, nil
+}
+
+// Stop stops the actor once the command it is running, if any, has finished. Commands sent after that
+// fail with ErrStopped. If ctx is done before the actor has stopped, Stop returns ctx.Err(), and the
+// actor still stops once the command finishes. It is safe to call Stop more than once.
+func (a *Actor[S]) Stop(ctx context.Context) error {
+	a.once.Do(func() {
+		close(a.stop)
+	})
+	select {
+	case <-a.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
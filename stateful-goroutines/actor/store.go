@@ -0,0 +1,61 @@
+package actor
+
+import "context"
+
+// Store is an actor that owns a map, with typed commands for the usual map operations. It is the
+// generic form of the stateful-goroutines example's map[int]int.
+type Store[K, V comparable] struct {
+	*Actor[map[K]V]
+}
+
+// NewStore starts a store with an empty map.
+func NewStore[K, V comparable]() *Store[K, V] {
+	return &Store[K, V]{New(make(map[K]V))}
+}
+
+// Get returns the value stored under key, or ErrNotFound.
+func (s *Store[K, V]) Get(ctx context.Context, key K) (V, error) {
+	return Ask(ctx, s.Actor, func(m *map[K]V) (V, error) {
+		v, ok := (*m)[key]
+		if !ok {
+			return v, ErrNotFound
+		}
+		return v, nil
+	})
+}
+
+// Set stores value under key.
+func (s *Store[K, V]) Set(ctx context.Context, key K, value V) error {
+	return s.Do(ctx, func(m *map[K]V) error {
+		(*m)[key] = value
+		return nil
+	})
+}
+
+// Delete removes key, reporting whether it was stored.
+func (s *Store[K, V]) Delete(ctx context.Context, key K) (bool, error) {
+	return Ask(ctx, s.Actor, func(m *map[K]V) (bool, error) {
+		_, ok := (*m)[key]
+		delete(*m, key)
+		return ok, nil
+	})
+}
+
+// CompareAndSwap stores new under key if the value stored there is old, reporting whether it did. A
+// key that isn't stored never matches.
+func (s *Store[K, V]) CompareAndSwap(ctx context.Context, key K, old, new V) (bool, error) {
+	return Ask(ctx, s.Actor, func(m *map[K]V) (bool, error) {
+		if v, ok := (*m)[key]; !ok || v != old {
+			return false, nil
+		}
+		(*m)[key] = new
+		return true, nil
+	})
+}
+
+// Len returns the number of stored keys.
+func (s *Store[K, V]) Len(ctx context.Context) (int, error) {
+	return Ask(ctx, s.Actor, func(m *map[K]V) (int, error) {
+		return len(*m), nil
+	})
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"sync/atomic"
 	"time"
+
+	"github.com/yanw2/go-by-example/stateful-goroutines/actor"
 )
 
 // In this example, our state will be owned by a single goroutine. This will guaranttee that the data
@@ -89,4 +93,46 @@ func main() {
 	fmt.Println("readOps:", readOpsFinal)
 	writeOpsFinal := atomic.LoadUint64(&writeOps)
 	fmt.Println("writeOps:", writeOpsFinal)
+
+	actors()
+}
+
+// The owner goroutine above only understands reads and writes of a `map[int]int`, and it never
+// exits. The `actor` package generalizes it: an `actor.Store` owns a map of any key and value types
+// and takes get, set, delete and compare-and-swap commands, and `Do` runs any function on the state.
+// Every command takes a context, and errors come back through the command's reply.
+func actors() {
+	ctx := context.Background()
+	store := actor.NewStore[string, int]()
+
+	store.Set(ctx, "a", 1)
+	swapped, _ := store.CompareAndSwap(ctx, "a", 1, 2)
+	fmt.Println("swapped:", swapped)
+	v, _ := store.Get(ctx, "a")
+	fmt.Println("a:", v)
+	if _, err := store.Get(ctx, "b"); errors.Is(err, actor.ErrNotFound) {
+		fmt.Println("b:", err)
+	}
+
+	// A custom command can look at the whole state at once, and its error is returned to the caller.
+	err := store.Do(ctx, func(m *map[string]int) error {
+		if (*m)["a"] > 1 {
+			return fmt.Errorf("a is too big: %d", (*m)["a"])
+		}
+		return nil
+	})
+	fmt.Println("check:", err)
+
+	// A caller can give up on a slow command through its context.
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	err = store.Do(timeout, func(m *map[string]int) error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	})
+	fmt.Println("slow command:", err)
+
+	// Once stopped, the actor's goroutine exits and further commands fail.
+	store.Stop(ctx)
+	fmt.Println("after stop:", store.Set(ctx, "a", 3))
 }
@@ -0,0 +1,232 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule decides when a job runs next.
+type Schedule interface {
+	// Next returns the first time strictly after t at which the job should run.
+	Next(t time.Time) time.Time
+}
+
+// Every returns a schedule that runs a job at a fixed interval. It panics if d is not positive.
+func Every(d time.Duration) Schedule {
+	if d <= 0 {
+		panic("schedule: non-positive interval")
+	}
+	return every(d)
+}
+
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// cron is a parsed cron expression. Each field is a bit set of the values it matches.
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar record whether the day fields start with "*", as "*" and "*/2" do. As in
+	// standard cron, such a field leaves the other one to decide which days match, even if it has a
+	// step; only when both day fields are restricted does a day match if either of them does.
+	domStar, dowStar bool
+}
+
+type field struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{min: 0, max: 59}
+	hourField   = field{min: 0, max: 23}
+	domField    = field{min: 1, max: 31}
+	monthField  = field{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = field{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a standard five-field cron expression: minute, hour, day of month, month and day
+// of week. Fields accept "*", numbers, ranges such as "1-5", steps such as "*/15" or "10-30/5", and
+// comma-separated lists of these. Months and days of week may also be given by their three-letter
+// English names, and Sunday is either 0 or 7. A day matches if it matches both day fields, unless
+// neither field starts with "*", in which case matching either is enough. The descriptors @yearly,
+// @monthly, @weekly, @daily and @hourly are accepted too, as is "@every <duration>" for a fixed
+// interval.
+func ParseCron(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if d, ok := strings.CutPrefix(expr, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil {
+			return nil, fmt.Errorf("schedule: %q: %w", expr, err)
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("schedule: %q: non-positive interval", expr)
+		}
+		return every(interval), nil
+	}
+	if std, ok := descriptors[strings.ToLower(expr)]; ok {
+		expr = std
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule: %q: expected 5 fields, found %d", expr, len(fields))
+	}
+
+	var c cron
+	var err error
+	parsers := []struct {
+		dst *uint64
+		f   field
+	}{
+		{&c.minute, minuteField},
+		{&c.hour, hourField},
+		{&c.dom, domField},
+		{&c.month, monthField},
+		{&c.dow, dowField},
+	}
+	for i, p := range parsers {
+		if *p.dst, err = p.f.parse(fields[i]); err != nil {
+			return nil, fmt.Errorf("schedule: %q: %w", expr, err)
+		}
+	}
+	// Sunday may be written as 7; fold it onto 0.
+	if c.dow&(1<<7) != 0 {
+		c.dow = c.dow&^(1<<7) | 1
+	}
+	c.domStar = strings.HasPrefix(fields[2], "*")
+	c.dowStar = strings.HasPrefix(fields[4], "*")
+	return &c, nil
+}
+
+// parse returns the bit set of values matched by one field of a cron expression.
+func (f field) parse(s string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+		}
+
+		var lo, hi int
+		switch {
+		case rng == "*":
+			lo, hi = f.min, f.max
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(a); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(b); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		default:
+			v, err := f.value(rng)
+			if err != nil {
+				return 0, err
+			}
+			lo, hi = v, v
+			// "5/15" means starting at 5, every 15 up to the maximum.
+			if hasStep {
+				hi = f.max
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (f field) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", v, f.min, f.max)
+	}
+	return v, nil
+}
+
+func has(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
+
+func (c *cron) dayMatches(t time.Time) bool {
+	dom := has(c.dom, t.Day())
+	dow := has(c.dow, int(t.Weekday()))
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first minute strictly after t that matches the expression, in t's location. It
+// returns the zero time if there is none within five years, such as for "0 0 30 2 *". The fields are
+// matched against wall-clock time, so a time skipped when clocks go forward for daylight saving
+// doesn't match that day, and one that happens twice when they go back matches both times.
+func (c *cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	// Truncate works on absolute time, so unlike time.Date it can't move t to the other occurrence of
+	// a wall-clock time that happens twice.
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	// Move forward one field at a time, resetting the smaller fields whenever a larger one changes.
+	for t.Before(limit) {
+		var next time.Time
+		switch {
+		case !has(c.month, int(t.Month())):
+			next = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			next = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case !has(c.hour, t.Hour()):
+			next = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case !has(c.minute, t.Minute()):
+			next = t.Add(time.Minute)
+		default:
+			return t
+		}
+		// time.Date turns a wall-clock time skipped by a daylight saving change into one before the
+		// change, so 2:00 on a day when clocks jump from 2:00 to 3:00 comes out as 1:00. Rather than
+		// going round in circles, carry on from the start of the next hour.
+		if !next.After(t) {
+			next = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		}
+		t = next
+	}
+	return time.Time{}
+}
@@ -0,0 +1,157 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func date(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		expr string
+		from time.Time
+		want []time.Time
+	}{
+		{"*/15 * * * *", date(2024, 1, 1, 10, 7), []time.Time{
+			date(2024, 1, 1, 10, 15), date(2024, 1, 1, 10, 30), date(2024, 1, 1, 10, 45), date(2024, 1, 1, 11, 0),
+		}},
+		{"10-30/10 8 * * *", date(2024, 1, 1, 0, 0), []time.Time{
+			date(2024, 1, 1, 8, 10), date(2024, 1, 1, 8, 20), date(2024, 1, 1, 8, 30), date(2024, 1, 2, 8, 10),
+		}},
+		{"5/20 * * * *", date(2024, 1, 1, 10, 0), []time.Time{
+			date(2024, 1, 1, 10, 5), date(2024, 1, 1, 10, 25), date(2024, 1, 1, 10, 45), date(2024, 1, 1, 11, 5),
+		}},
+		{"0,30 9 * * *", date(2024, 1, 1, 9, 0), []time.Time{
+			date(2024, 1, 1, 9, 30), date(2024, 1, 2, 9, 0),
+		}},
+		// Names are case-insensitive; 2024-01-31 is a Wednesday and 2024-07-01 a Monday.
+		{"0 9 * jan,JUL mon-fri", date(2024, 1, 31, 10, 0), []time.Time{
+			date(2024, 7, 1, 9, 0), date(2024, 7, 2, 9, 0),
+		}},
+		// 7 is Sunday as well as 0.
+		{"0 0 * * 7", date(2024, 1, 1, 0, 0), []time.Time{
+			date(2024, 1, 7, 0, 0), date(2024, 1, 14, 0, 0),
+		}},
+		{"0 0 * * 5-7", date(2024, 1, 1, 0, 0), []time.Time{
+			date(2024, 1, 5, 0, 0), date(2024, 1, 6, 0, 0), date(2024, 1, 7, 0, 0), date(2024, 1, 12, 0, 0),
+		}},
+		// A day field starting with "*" leaves the other to decide, even with a step: these are the
+		// Mondays on odd days of the month.
+		{"0 0 */2 * mon", date(2024, 1, 1, 0, 0), []time.Time{
+			date(2024, 1, 15, 0, 0), date(2024, 1, 29, 0, 0), date(2024, 2, 5, 0, 0), date(2024, 2, 19, 0, 0),
+		}},
+		// The first of the month, when it falls on a Sunday, Tuesday, Thursday or Saturday.
+		{"0 0 1 * */2", date(2024, 1, 1, 0, 0), []time.Time{
+			date(2024, 2, 1, 0, 0), date(2024, 6, 1, 0, 0), date(2024, 8, 1, 0, 0),
+		}},
+		// With both day fields restricted, either one matching is enough.
+		{"0 0 13 * fri", date(2024, 1, 1, 0, 0), []time.Time{
+			date(2024, 1, 5, 0, 0), date(2024, 1, 12, 0, 0), date(2024, 1, 13, 0, 0), date(2024, 1, 19, 0, 0),
+		}},
+		{"@weekly", date(2024, 1, 3, 12, 0), []time.Time{date(2024, 1, 7, 0, 0)}},
+		{"@hourly", date(2024, 1, 1, 23, 59), []time.Time{date(2024, 1, 2, 0, 0)}},
+		{"@every 90m", date(2024, 1, 1, 10, 0), []time.Time{date(2024, 1, 1, 11, 30), date(2024, 1, 1, 13, 0)}},
+		{"0 0 29 2 *", date(2024, 3, 1, 0, 0), []time.Time{date(2028, 2, 29, 0, 0)}},
+		{"0 0 30 2 *", date(2024, 1, 1, 0, 0), []time.Time{{}}},
+		{"0 0 31 4,6,9,11 *", date(2024, 1, 1, 0, 0), []time.Time{{}}},
+	}
+	for _, tt := range tests {
+		s, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", tt.expr, err)
+			continue
+		}
+		from := tt.from
+		for _, want := range tt.want {
+			got := s.Next(from)
+			if !got.Equal(want) {
+				t.Errorf("%q: Next(%v) = %v, want %v", tt.expr, from, got, want)
+				break
+			}
+			from = got
+		}
+	}
+}
+
+func TestCronNextAcrossDaylightSaving(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2024, month, day, hour, min, 0, 0, ny)
+	}
+	// In 2024 New York's clocks went from 2:00 to 3:00 on March 10, and from 2:00 back to 1:00 on
+	// November 3.
+	fallBack := at(11, 3, 1, 30)
+	if _, offset := fallBack.Zone(); offset != -4*60*60 {
+		fallBack = fallBack.Add(-time.Hour)
+	}
+
+	tests := []struct {
+		expr string
+		from time.Time
+		want []time.Time
+	}{
+		{"30 2 * * *", at(3, 9, 3, 0), []time.Time{at(3, 11, 2, 30)}},
+		{"0 * * * *", at(3, 10, 0, 30), []time.Time{at(3, 10, 1, 0), at(3, 10, 3, 0), at(3, 10, 4, 0)}},
+		{"30 1 * * *", at(11, 3, 0, 0), []time.Time{fallBack, fallBack.Add(time.Hour), at(11, 4, 1, 30)}},
+	}
+	for _, tt := range tests {
+		s, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", tt.expr, err)
+		}
+		from := tt.from
+		for _, want := range tt.want {
+			got := s.Next(from)
+			if !got.Equal(want) || got.Location() != ny {
+				t.Errorf("%q: Next(%v) = %v, want %v", tt.expr, from, got, want)
+				break
+			}
+			from = got
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"@every",
+		"@every x",
+		"@every -1s",
+		"@sometimes",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestEvery(t *testing.T) {
+	from := date(2024, 1, 1, 10, 0)
+	if got, want := Every(time.Minute).Next(from), date(2024, 1, 1, 10, 1); !got.Equal(want) {
+		t.Fatalf("Every(time.Minute).Next = %v, want %v", got, want)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Every(0) didn't panic")
+		}
+	}()
+	Every(0)
+}
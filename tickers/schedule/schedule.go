@@ -0,0 +1,302 @@
+// Package schedule runs named jobs on cron expressions or fixed intervals, building on the timers and
+// tickers examples. Each job has its own timer, optional jitter and a policy for runs that come due
+// while the previous one is still going. The scheduler can be paused, resumed and stopped.
+package schedule
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+var (
+	// ErrStopped is returned by Add once the scheduler has been stopped.
+	ErrStopped = errors.New("schedule: scheduler stopped")
+
+	// ErrDuplicate is returned by Add if a job with the same name is already scheduled.
+	ErrDuplicate = errors.New("schedule: duplicate job name")
+)
+
+// Overlap says what happens when a job comes due while its previous run is still going.
+type Overlap int
+
+const (
+	// Skip drops the run that came due. This is the default.
+	Skip Overlap = iota
+	// Queue runs the job again as soon as the previous run finishes, once for every run that came
+	// due in the meantime.
+	Queue
+)
+
+// Option configures a Scheduler.
+type Option func(*Scheduler)
+
+// WithClock sets the clock that runs are timed against. The default is the system clock. Cron
+// schedules are matched in the location of the times the clock returns.
+func WithClock(clk clock.Clock) Option {
+	return func(s *Scheduler) {
+		s.clock = clk
+	}
+}
+
+// JobOption configures a single job passed to Add.
+type JobOption func(*job)
+
+// WithJitter delays every run of the job by a random duration in [0, d), which spreads out jobs that
+// would otherwise all fire at the same moment. The schedule itself doesn't drift.
+func WithJitter(d time.Duration) JobOption {
+	return func(j *job) {
+		j.jitter = d
+	}
+}
+
+// WithOverlap sets the job's overlap policy.
+func WithOverlap(o Overlap) JobOption {
+	return func(j *job) {
+		j.overlap = o
+	}
+}
+
+// JobInfo describes a scheduled job. Runs counts completed runs and Skipped the runs that were dropped
+// because of the overlap policy or because the scheduler was paused.
+type JobInfo struct {
+	Name    string
+	Next    time.Time
+	Running bool
+	Runs    int
+	Skipped int
+}
+
+type job struct {
+	name     string
+	schedule Schedule
+	fn       func(context.Context)
+	jitter   time.Duration
+	overlap  Overlap
+	remove   chan struct{}
+
+	// The fields below are guarded by the scheduler's mutex.
+	next    time.Time
+	running bool
+	pending int
+	runs    int
+	skipped int
+}
+
+// Scheduler runs jobs on their schedules. It is safe for concurrent use.
+type Scheduler struct {
+	clock  clock.Clock
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	jobs    map[string]*job
+	paused  bool
+	stopped bool
+
+	// loops tracks the per-job timer goroutines and runs the job runs in progress.
+	loops sync.WaitGroup
+	runs  sync.WaitGroup
+}
+
+// New returns a scheduler with no jobs.
+func New(opts ...Option) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		clock:  clock.Real(),
+		ctx:    ctx,
+		cancel: cancel,
+		jobs:   make(map[string]*job),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Add schedules fn to run under the given name. The context passed to fn is cancelled if the
+// scheduler is stopped before the run finishes.
+func (s *Scheduler) Add(name string, schedule Schedule, fn func(ctx context.Context), opts ...JobOption) error {
+	j := &job{
+		name:     name,
+		schedule: schedule,
+		fn:       fn,
+		remove:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return ErrStopped
+	}
+	if _, ok := s.jobs[name]; ok {
+		return ErrDuplicate
+	}
+	s.jobs[name] = j
+	s.loops.Add(1)
+	go s.loop(j, s.clock.Now())
+	return nil
+}
+
+// Remove unschedules the named job, reporting whether it was scheduled. A run in progress is allowed
+// to finish, but queued runs are dropped.
+func (s *Scheduler) Remove(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return false
+	}
+	delete(s.jobs, name)
+	j.pending = 0
+	close(j.remove)
+	return true
+}
+
+// loop waits for each of the job's runs to come due until the job is removed or the scheduler
+// stopped. base is the unjittered time of the previous run, so jitter never accumulates.
+func (s *Scheduler) loop(j *job, base time.Time) {
+	defer s.loops.Done()
+	for {
+		now := s.clock.Now()
+		base = j.schedule.Next(base)
+		if base.Before(now) {
+			// We fell behind, for instance because the clock jumped; skip ahead rather than firing
+			// every missed run at once.
+			base = j.schedule.Next(now)
+		}
+		if base.IsZero() {
+			return
+		}
+		at := base
+		if j.jitter > 0 {
+			at = at.Add(rand.N(j.jitter))
+		}
+
+		s.mu.Lock()
+		j.next = at
+		s.mu.Unlock()
+
+		t := s.clock.NewTimer(at.Sub(now))
+		select {
+		case <-t.Chan():
+			s.fire(j)
+		case <-j.remove:
+			t.Stop()
+			return
+		case <-s.ctx.Done():
+			t.Stop()
+			return
+		}
+	}
+}
+
+// fire starts a run of the job, or applies its overlap policy if the previous run is still going.
+func (s *Scheduler) fire(j *job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case s.paused || s.stopped:
+		j.skipped++
+	case j.running && j.overlap == Queue:
+		j.pending++
+	case j.running:
+		j.skipped++
+	default:
+		j.running = true
+		s.runs.Add(1)
+		go s.run(j)
+	}
+}
+
+// run runs the job, then once more for each run that was queued meanwhile.
+func (s *Scheduler) run(j *job) {
+	defer s.runs.Done()
+	for {
+		j.fn(s.ctx)
+
+		s.mu.Lock()
+		j.runs++
+		if j.pending == 0 || s.stopped {
+			j.pending = 0
+			j.running = false
+			s.mu.Unlock()
+			return
+		}
+		j.pending--
+		s.mu.Unlock()
+	}
+}
+
+// Pause stops jobs from starting until Resume is called. Runs that come due while paused are skipped
+// rather than caught up on afterwards; runs in progress are not interrupted.
+func (s *Scheduler) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = true
+}
+
+// Resume lets jobs start again after Pause.
+func (s *Scheduler) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = false
+}
+
+// Jobs returns information about every scheduled job, sorted by name.
+func (s *Scheduler) Jobs() []JobInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	infos := make([]JobInfo, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		infos = append(infos, JobInfo{
+			Name:    j.name,
+			Next:    j.next,
+			Running: j.running,
+			Runs:    j.runs,
+			Skipped: j.skipped,
+		})
+	}
+	slices.SortFunc(infos, func(a, b JobInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return infos
+}
+
+// Stop stops scheduling new runs and waits for the runs in progress to finish. Queued runs are
+// dropped. If ctx is done first, the context passed to the running jobs is cancelled and ctx.Err()
+// is returned.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	for name, j := range s.jobs {
+		delete(s.jobs, name)
+		close(j.remove)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.loops.Wait()
+		s.runs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,236 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newScheduler returns a scheduler on a fake clock set to start. It is stopped when the test ends,
+// cancelling any runs that are still blocked.
+func newScheduler(t *testing.T) (*Scheduler, *clock.Fake) {
+	fake := clock.NewFake(start)
+	s := New(WithClock(fake))
+	t.Cleanup(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		s.Stop(ctx)
+	})
+	return s, fake
+}
+
+// blockingJob returns a job that reports on started each time it starts and then blocks until it
+// receives from release or its context is cancelled.
+func blockingJob() (fn func(context.Context), started, release chan struct{}) {
+	started = make(chan struct{}, 10)
+	release = make(chan struct{})
+	fn = func(ctx context.Context) {
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+	}
+	return fn, started, release
+}
+
+func blockUntil(t *testing.T, fake *clock.Fake, n int) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		fake.BlockUntil(n)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for %d timers on the clock", n)
+	}
+}
+
+// tick moves the clock on by a minute once the job's timer is set, and waits for the timer of the
+// run after that. Each job sets a new timer only once it has dealt with the previous one, so when
+// tick returns the job has either started or had its overlap policy applied.
+func tick(t *testing.T, fake *clock.Fake) {
+	t.Helper()
+	blockUntil(t, fake, 1)
+	fake.Advance(time.Minute)
+	blockUntil(t, fake, 1)
+}
+
+func receive(t *testing.T, c <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-c:
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+func notStarted(t *testing.T, started <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-started:
+		t.Fatal("job started, want it not to")
+	default:
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func info(s *Scheduler) JobInfo {
+	return s.Jobs()[0]
+}
+
+func TestSkipDropsRunsThatComeDueWhileRunning(t *testing.T) {
+	s, fake := newScheduler(t)
+	fn, started, release := blockingJob()
+	if err := s.Add("job", Every(time.Minute), fn); err != nil {
+		t.Fatal(err)
+	}
+
+	tick(t, fake)
+	receive(t, started, "the first run")
+	if got, want := info(s).Next, start.Add(2*time.Minute); !got.Equal(want) {
+		t.Fatalf("Next = %v, want %v", got, want)
+	}
+	tick(t, fake)
+	tick(t, fake)
+	notStarted(t, started)
+	if ji := info(s); !ji.Running || ji.Runs != 0 || ji.Skipped != 2 {
+		t.Fatalf("Jobs = %+v, want it running with 0 runs and 2 skipped", ji)
+	}
+
+	release <- struct{}{}
+	waitFor(t, "the run to finish", func() bool { return info(s).Runs == 1 })
+	notStarted(t, started)
+
+	tick(t, fake)
+	receive(t, started, "the run after the skipped ones")
+}
+
+func TestQueueRunsOnceForEveryRunThatCameDue(t *testing.T) {
+	s, fake := newScheduler(t)
+	fn, started, release := blockingJob()
+	if err := s.Add("job", Every(time.Minute), fn, WithOverlap(Queue)); err != nil {
+		t.Fatal(err)
+	}
+
+	tick(t, fake)
+	receive(t, started, "the first run")
+	tick(t, fake)
+	tick(t, fake)
+	notStarted(t, started)
+
+	for i := 0; i < 2; i++ {
+		release <- struct{}{}
+		receive(t, started, "a queued run")
+	}
+	release <- struct{}{}
+	waitFor(t, "the queued runs to finish", func() bool {
+		ji := info(s)
+		return ji.Runs == 3 && !ji.Running
+	})
+	notStarted(t, started)
+	if ji := info(s); ji.Skipped != 0 {
+		t.Fatalf("Skipped = %d, want 0", ji.Skipped)
+	}
+}
+
+func TestPauseSkipsRunsUntilResume(t *testing.T) {
+	s, fake := newScheduler(t)
+	fn, started, _ := blockingJob()
+	if err := s.Add("job", Every(time.Minute), fn); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Pause()
+	tick(t, fake)
+	tick(t, fake)
+	notStarted(t, started)
+	if ji := info(s); ji.Skipped != 2 {
+		t.Fatalf("Skipped = %d while paused, want 2", ji.Skipped)
+	}
+
+	// Runs missed while paused are not caught up on.
+	s.Resume()
+	notStarted(t, started)
+	tick(t, fake)
+	receive(t, started, "the run after Resume")
+}
+
+func TestStopWaitsForRunsInProgressAndDropsQueuedOnes(t *testing.T) {
+	s, fake := newScheduler(t)
+	fn, started, release := blockingJob()
+	if err := s.Add("job", Every(time.Minute), fn, WithOverlap(Queue)); err != nil {
+		t.Fatal(err)
+	}
+	tick(t, fake)
+	receive(t, started, "the first run")
+	tick(t, fake)
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Stop(context.Background()) }()
+	select {
+	case err := <-stopped:
+		t.Fatalf("Stop returned %v while a run was in progress", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	release <- struct{}{}
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatalf("Stop = %v, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Stop didn't return after the run finished")
+	}
+	notStarted(t, started)
+
+	if jobs := s.Jobs(); len(jobs) != 0 {
+		t.Fatalf("Jobs after Stop = %+v, want none", jobs)
+	}
+	if err := s.Add("other", Every(time.Minute), fn); !errors.Is(err, ErrStopped) {
+		t.Fatalf("Add after Stop = %v, want ErrStopped", err)
+	}
+	fake.Advance(time.Hour)
+	notStarted(t, started)
+}
+
+func TestStopCancelsRunsWhenItsContextIsDone(t *testing.T) {
+	s, fake := newScheduler(t)
+	cancelled := make(chan struct{})
+	started := make(chan struct{}, 1)
+	err := s.Add("job", Every(time.Minute), func(ctx context.Context) {
+		started <- struct{}{}
+		<-ctx.Done()
+		close(cancelled)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tick(t, fake)
+	receive(t, started, "the run")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Stop = %v, want context.DeadlineExceeded", err)
+	}
+	receive(t, cancelled, "the run's context to be cancelled")
+}
@@ -1,13 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+	"github.com/yanw2/go-by-example/tickers/schedule"
 )
 
 func main() {
+	run(clock.Real())
+	scheduler(clock.Real())
+}
+
+func run(clk clock.Clock) {
 	// `tickers` are for when you want to do something repeatedly at regular intervals.
-	ticker := time.NewTicker(500 * time.Millisecond)
+	ticker := clk.NewTicker(500 * time.Millisecond)
 	done := make(chan bool)
 
 	go func() {
@@ -15,14 +24,59 @@ func main() {
 			select {
 			case <-done:
 				return
-			case t := <-ticker.C:
+			case t := <-ticker.Chan():
 				fmt.Println("tick at", t)
 			}
 		}
 	}()
 
-	time.Sleep(1600 * time.Millisecond)
+	clk.Sleep(1600 * time.Millisecond)
 	ticker.Stop()
 	done <- true
 	fmt.Println("ticker stopped")
 }
+
+// The `schedule` package builds a scheduler for named jobs on top of timers. Each job runs on a fixed
+// interval or a cron expression, and has a policy for runs that come due while the previous run is
+// still going.
+func scheduler(clk clock.Clock) {
+	s := schedule.New(schedule.WithClock(clk))
+
+	s.Add("tick", schedule.Every(500*time.Millisecond), func(ctx context.Context) {
+		fmt.Println("tick at", clk.Now())
+	})
+
+	// This job takes longer than its interval. With the `Skip` policy runs that come due while it is
+	// still going are dropped; `Queue` would run them back to back instead.
+	s.Add("slow", schedule.Every(200*time.Millisecond), func(ctx context.Context) {
+		clk.Sleep(500 * time.Millisecond)
+		fmt.Println("slow job done")
+	}, schedule.WithOverlap(schedule.Skip), schedule.WithJitter(50*time.Millisecond))
+
+	clk.Sleep(1600 * time.Millisecond)
+
+	// While paused, jobs that come due are skipped.
+	s.Pause()
+	clk.Sleep(time.Second)
+	s.Resume()
+
+	for _, j := range s.Jobs() {
+		fmt.Println(j.Name, "runs:", j.Runs, "skipped:", j.Skipped)
+	}
+
+	// `Stop` stops scheduling new runs and waits for the ones in progress to finish.
+	s.Stop(context.Background())
+	fmt.Println("scheduler stopped")
+
+	// Cron expressions use the usual five fields: minute, hour, day of month, month and day of week.
+	// Here is when a job running every quarter of an hour during office hours would next run.
+	cron, err := schedule.ParseCron("*/15 9-17 * * mon-fri")
+	if err != nil {
+		panic(err)
+	}
+	t := time.Date(2019, time.March, 1, 17, 40, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		t = cron.Next(t)
+		fmt.Println("next run at", t)
+	}
+}
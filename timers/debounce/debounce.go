@@ -0,0 +1,220 @@
+// Package debounce provides Debouncer and Throttler, two reusable ways of limiting how often a function
+// runs in response to a burst of events, built on the timers example.
+//
+// Each type keeps its timers inside a single owner goroutine, as in the stateful-goroutines example,
+// so the usual time.Timer pitfalls around Stop and Reset are handled in one place: see resetTimer.
+package debounce
+
+import (
+	"sync"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+// Option configures a Debouncer or Throttler.
+type Option func(*config)
+
+type config struct {
+	clock    clock.Clock
+	maxWait  time.Duration
+	leading  bool
+	trailing bool
+}
+
+func newConfig(opts []Option) config {
+	c := config{clock: clock.Real(), leading: true, trailing: true}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// WithClock sets the clock the wait and interval timers run on. The default is the system clock.
+func WithClock(clk clock.Clock) Option {
+	return func(c *config) {
+		c.clock = clk
+	}
+}
+
+// WithMaxWait bounds how long a Debouncer may keep postponing the call while triggers keep arriving.
+// Once d has passed since the first trigger of a burst, the function runs even if the burst hasn't
+// gone quiet. It has no effect on a Throttler.
+func WithMaxWait(d time.Duration) Option {
+	return func(c *config) {
+		c.maxWait = d
+	}
+}
+
+// WithLeading sets whether a Throttler runs the function immediately on the first trigger of an
+// interval. The default is true. It has no effect on a Debouncer.
+func WithLeading(leading bool) Option {
+	return func(c *config) {
+		c.leading = leading
+	}
+}
+
+// WithTrailing sets whether a Throttler runs the function once more at the end of an interval in which
+// further triggers arrived. The default is true. It has no effect on a Debouncer.
+func WithTrailing(trailing bool) Option {
+	return func(c *config) {
+		c.trailing = trailing
+	}
+}
+
+// stopTimer stops t and drains the value it may already have delivered to its channel. Without the
+// drain, a later receive could see that stale value and fire straight away. This is only safe because
+// the owner goroutine is the timer's sole receiver, so the value can't be taken between the two steps.
+func stopTimer(t clock.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.Chan():
+		default:
+		}
+	}
+}
+
+// resetTimer restarts t to fire after d. A timer must be stopped and drained before Reset, otherwise
+// a value from its previous run may still be waiting in the channel.
+func resetTimer(t clock.Timer, d time.Duration) {
+	stopTimer(t)
+	t.Reset(d)
+}
+
+// newStoppedTimer returns a timer that is not running, ready to be started with resetTimer.
+func newStoppedTimer(clk clock.Clock) clock.Timer {
+	t := clk.NewTimer(time.Hour)
+	stopTimer(t)
+	return t
+}
+
+// Debouncer runs a function once a burst of triggers has gone quiet for the wait duration. It is safe
+// for concurrent use. The function runs on the debouncer's own goroutine, so calls never overlap;
+// triggers that arrive while it is running start a new burst once it returns.
+type Debouncer struct {
+	fn      func()
+	wait    time.Duration
+	maxWait time.Duration
+	clock   clock.Clock
+
+	trigger chan struct{}
+	flush   chan chan bool
+	cancel  chan chan bool
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewDebouncer returns a debouncer that runs fn after wait has passed without a trigger.
+func NewDebouncer(wait time.Duration, fn func(), opts ...Option) *Debouncer {
+	c := newConfig(opts)
+	d := &Debouncer{
+		fn:      fn,
+		wait:    wait,
+		maxWait: c.maxWait,
+		clock:   c.clock,
+		trigger: make(chan struct{}, 1),
+		flush:   make(chan chan bool),
+		cancel:  make(chan chan bool),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go d.loop()
+	return d
+}
+
+func (d *Debouncer) loop() {
+	defer close(d.done)
+	wait := newStoppedTimer(d.clock)
+	defer stopTimer(wait)
+	maxTimer := newStoppedTimer(d.clock)
+	defer stopTimer(maxTimer)
+
+	// The timer channels are only selected on while a call is pending, so a stopped timer is never
+	// waited on.
+	pending := false
+	reset := func() {
+		pending = false
+		stopTimer(wait)
+		stopTimer(maxTimer)
+	}
+	fire := func() {
+		reset()
+		d.fn()
+	}
+
+	for {
+		var waitC, maxC <-chan time.Time
+		if pending {
+			waitC = wait.Chan()
+			if d.maxWait > 0 {
+				maxC = maxTimer.Chan()
+			}
+		}
+
+		select {
+		case <-d.trigger:
+			resetTimer(wait, d.wait)
+			if !pending && d.maxWait > 0 {
+				resetTimer(maxTimer, d.maxWait)
+			}
+			pending = true
+		case <-waitC:
+			fire()
+		case <-maxC:
+			fire()
+		case resp := <-d.flush:
+			ran := pending
+			if pending {
+				fire()
+			}
+			resp <- ran
+		case resp := <-d.cancel:
+			was := pending
+			reset()
+			resp <- was
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// Trigger starts or extends the current burst. It never blocks for long, and does nothing once the
+// debouncer is stopped.
+func (d *Debouncer) Trigger() {
+	select {
+	case d.trigger <- struct{}{}:
+	case <-d.done:
+	default:
+		// A trigger is already waiting to be handled, which has the same effect.
+	}
+}
+
+// Flush runs the function straight away if a call is pending, reporting whether it did.
+func (d *Debouncer) Flush() bool {
+	return d.request(d.flush)
+}
+
+// Cancel drops the pending call, if any, reporting whether there was one.
+func (d *Debouncer) Cancel() bool {
+	return d.request(d.cancel)
+}
+
+func (d *Debouncer) request(c chan chan bool) bool {
+	resp := make(chan bool, 1)
+	select {
+	case c <- resp:
+		return <-resp
+	case <-d.done:
+		return false
+	}
+}
+
+// Stop drops any pending call and stops the debouncer's goroutine and timers. If the function is
+// running, Stop waits for it to return. It is safe to call Stop more than once.
+func (d *Debouncer) Stop() {
+	d.once.Do(func() {
+		close(d.stop)
+	})
+	<-d.done
+}
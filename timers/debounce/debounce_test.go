@@ -0,0 +1,204 @@
+package debounce
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+// watchedClock is a fake clock whose timers report every Reset. Triggers are handled on the owner
+// goroutine, so a test that moved the fake time straight after Trigger could do so before the timer
+// was set; waiting for the reset first makes the deadline deterministic.
+type watchedClock struct {
+	*clock.Fake
+	resets chan time.Duration
+}
+
+func newWatchedClock() *watchedClock {
+	return &watchedClock{Fake: clock.NewFake(time.Now()), resets: make(chan time.Duration, 10)}
+}
+
+func (c *watchedClock) NewTimer(d time.Duration) clock.Timer {
+	return &watchedTimer{Timer: c.Fake.NewTimer(d), resets: c.resets}
+}
+
+type watchedTimer struct {
+	clock.Timer
+	resets chan<- time.Duration
+}
+
+func (t *watchedTimer) Reset(d time.Duration) bool {
+	active := t.Timer.Reset(d)
+	t.resets <- d
+	return active
+}
+
+// expectResets waits for timers to be reset to each of the given durations, in order.
+func expectResets(t *testing.T, c *watchedClock, want ...time.Duration) {
+	t.Helper()
+	for _, w := range want {
+		select {
+		case d := <-c.resets:
+			if d != w {
+				t.Fatalf("timer reset to %v, want %v", d, w)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for a timer to be reset to %v", w)
+		}
+	}
+}
+
+// counter returns a function that reports each call on the returned channel.
+func counter() (func(), chan struct{}) {
+	calls := make(chan struct{}, 10)
+	return func() { calls <- struct{}{} }, calls
+}
+
+func expectCall(t *testing.T, calls <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-calls:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the function to run")
+	}
+}
+
+// expectNoCall fails the test if the function runs. Fake time is only moved by the test, so a short
+// real-time wait is enough to catch a call that shouldn't have happened.
+func expectNoCall(t *testing.T, calls <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-calls:
+		t.Fatal("the function ran, want it not to")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestDebounceRunsOnceTheBurstGoesQuiet(t *testing.T) {
+	c := newWatchedClock()
+	fn, calls := counter()
+	d := NewDebouncer(100*time.Millisecond, fn, WithClock(c))
+	defer d.Stop()
+
+	d.Trigger()
+	expectResets(t, c, 100*time.Millisecond)
+	c.Advance(60 * time.Millisecond)
+	d.Trigger()
+	expectResets(t, c, 100*time.Millisecond)
+
+	c.Advance(99 * time.Millisecond)
+	expectNoCall(t, calls)
+	c.Advance(time.Millisecond)
+	expectCall(t, calls)
+
+	c.Advance(time.Second)
+	expectNoCall(t, calls)
+}
+
+func TestDebounceMaxWaitRunsDuringABurst(t *testing.T) {
+	c := newWatchedClock()
+	fn, calls := counter()
+	d := NewDebouncer(100*time.Millisecond, fn, WithMaxWait(250*time.Millisecond), WithClock(c))
+	defer d.Stop()
+
+	d.Trigger()
+	expectResets(t, c, 100*time.Millisecond, 250*time.Millisecond)
+	for i := 0; i < 4; i++ {
+		c.Advance(50 * time.Millisecond)
+		d.Trigger()
+		expectResets(t, c, 100*time.Millisecond)
+	}
+	expectNoCall(t, calls)
+	c.Advance(50 * time.Millisecond)
+	expectCall(t, calls)
+
+	// The call ends the burst, so the next trigger starts a new one with its own max wait.
+	d.Trigger()
+	expectResets(t, c, 100*time.Millisecond, 250*time.Millisecond)
+	c.Advance(100 * time.Millisecond)
+	expectCall(t, calls)
+}
+
+func TestDebounceFlushAndCancel(t *testing.T) {
+	c := newWatchedClock()
+	fn, calls := counter()
+	d := NewDebouncer(100*time.Millisecond, fn, WithClock(c))
+	defer d.Stop()
+
+	if d.Flush() {
+		t.Fatal("Flush = true with no call pending")
+	}
+	if d.Cancel() {
+		t.Fatal("Cancel = true with no call pending")
+	}
+
+	d.Trigger()
+	expectResets(t, c, 100*time.Millisecond)
+	if !d.Flush() {
+		t.Fatal("Flush = false with a call pending")
+	}
+	// Flush runs the function before it returns.
+	select {
+	case <-calls:
+	default:
+		t.Fatal("Flush returned before the function ran")
+	}
+	if d.Flush() {
+		t.Fatal("second Flush = true, want the call already made")
+	}
+	c.Advance(time.Second)
+	expectNoCall(t, calls)
+
+	d.Trigger()
+	expectResets(t, c, 100*time.Millisecond)
+	if !d.Cancel() {
+		t.Fatal("Cancel = false with a call pending")
+	}
+	if d.Flush() {
+		t.Fatal("Flush = true after Cancel")
+	}
+	c.Advance(time.Second)
+	expectNoCall(t, calls)
+}
+
+func TestDebounceStopWaitsForTheRunningCall(t *testing.T) {
+	c := newWatchedClock()
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	d := NewDebouncer(100*time.Millisecond, func() {
+		started <- struct{}{}
+		<-release
+	}, WithClock(c))
+
+	d.Trigger()
+	expectResets(t, c, 100*time.Millisecond)
+	c.Advance(100 * time.Millisecond)
+	expectCall(t, started)
+	// This trigger arrives while the function is running and is dropped by Stop.
+	d.Trigger()
+
+	stopped := make(chan struct{})
+	go func() {
+		d.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned while the function was running")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop didn't return after the function did")
+	}
+
+	d.Trigger()
+	if d.Flush() {
+		t.Fatal("Flush = true after Stop")
+	}
+	c.Advance(time.Second)
+	expectNoCall(t, started)
+	d.Stop()
+}
@@ -0,0 +1,105 @@
+package debounce
+
+import (
+	"sync"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+// Throttler runs a function at most once per interval however often it is triggered. By default the
+// first trigger runs the function immediately (the leading edge), and if more triggers arrive during
+// the interval it runs once more when the interval ends (the trailing edge). It is safe for concurrent
+// use. The function runs on the throttler's own goroutine, so calls never overlap.
+type Throttler struct {
+	fn       func()
+	interval time.Duration
+	leading  bool
+	trailing bool
+	clock    clock.Clock
+
+	trigger chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewThrottler returns a throttler that runs fn at most once per interval.
+func NewThrottler(interval time.Duration, fn func(), opts ...Option) *Throttler {
+	c := newConfig(opts)
+	t := &Throttler{
+		fn:       fn,
+		interval: interval,
+		leading:  c.leading,
+		trailing: c.trailing,
+		clock:    c.clock,
+		trigger:  make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go t.loop()
+	return t
+}
+
+func (t *Throttler) loop() {
+	defer close(t.done)
+	window := newStoppedTimer(t.clock)
+	defer stopTimer(window)
+
+	// While active, calls are being throttled until the window timer fires. pending records a trigger
+	// that arrived in the meantime and is owed a trailing call.
+	active, pending := false, false
+	for {
+		var windowC <-chan time.Time
+		if active {
+			windowC = window.Chan()
+		}
+
+		select {
+		case <-t.trigger:
+			if active {
+				pending = t.trailing
+				continue
+			}
+			if t.leading {
+				t.fn()
+			} else {
+				pending = t.trailing
+			}
+			active = true
+			resetTimer(window, t.interval)
+		case <-windowC:
+			if pending {
+				// The trailing call opens a new interval of its own, so triggers straight after it
+				// are throttled too.
+				pending = false
+				t.fn()
+				resetTimer(window, t.interval)
+				continue
+			}
+			active = false
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+// Trigger asks for the function to run, subject to throttling. It never blocks for long, and does
+// nothing once the throttler is stopped.
+func (t *Throttler) Trigger() {
+	select {
+	case t.trigger <- struct{}{}:
+	case <-t.done:
+	default:
+		// A trigger is already waiting to be handled, which has the same effect.
+	}
+}
+
+// Stop drops any pending trailing call and stops the throttler's goroutine and timer. If the function
+// is running, Stop waits for it to return. It is safe to call Stop more than once.
+func (t *Throttler) Stop() {
+	t.once.Do(func() {
+		close(t.stop)
+	})
+	<-t.done
+}
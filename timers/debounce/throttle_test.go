@@ -0,0 +1,118 @@
+package debounce
+
+import (
+	"testing"
+	"time"
+)
+
+// expectHandled waits for the throttler to pick up the trigger waiting in its channel. The owner
+// goroutine handles a trigger fully before it looks at the window timer again, so once the channel
+// is empty the trigger has been counted.
+func expectHandled(t *testing.T, th *Throttler) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for len(th.trigger) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the trigger to be handled")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestThrottleLeadingAndTrailing(t *testing.T) {
+	tests := []struct {
+		name              string
+		leading, trailing bool
+	}{
+		{"both", true, true},
+		{"leading", true, false},
+		{"trailing", false, true},
+		{"neither", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newWatchedClock()
+			fn, calls := counter()
+			th := NewThrottler(100*time.Millisecond, fn,
+				WithLeading(tt.leading), WithTrailing(tt.trailing), WithClock(c))
+			defer th.Stop()
+
+			// A burst of triggers runs the function at most once at the start of the interval...
+			th.Trigger()
+			expectResets(t, c, 100*time.Millisecond)
+			if tt.leading {
+				expectCall(t, calls)
+			}
+			for i := 0; i < 3; i++ {
+				th.Trigger()
+				expectHandled(t, th)
+			}
+			expectNoCall(t, calls)
+
+			// ...and at most once at the end, which starts an interval of its own.
+			c.Advance(100 * time.Millisecond)
+			if tt.trailing {
+				expectCall(t, calls)
+				expectResets(t, c, 100*time.Millisecond)
+			}
+			c.Advance(100 * time.Millisecond)
+			expectNoCall(t, calls)
+
+			// Once an interval passes without triggers, the next one starts afresh.
+			th.Trigger()
+			expectResets(t, c, 100*time.Millisecond)
+			if tt.leading {
+				expectCall(t, calls)
+			} else {
+				expectNoCall(t, calls)
+			}
+		})
+	}
+}
+
+func TestThrottleRunsTrailingCallForALoneTrigger(t *testing.T) {
+	c := newWatchedClock()
+	fn, calls := counter()
+	th := NewThrottler(100*time.Millisecond, fn, WithLeading(false), WithClock(c))
+	defer th.Stop()
+
+	th.Trigger()
+	expectResets(t, c, 100*time.Millisecond)
+	c.Advance(99 * time.Millisecond)
+	expectNoCall(t, calls)
+	c.Advance(time.Millisecond)
+	expectCall(t, calls)
+}
+
+func TestThrottleStopWaitsForTheRunningCall(t *testing.T) {
+	c := newWatchedClock()
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	th := NewThrottler(100*time.Millisecond, func() {
+		started <- struct{}{}
+		<-release
+	}, WithClock(c))
+
+	th.Trigger()
+	expectCall(t, started)
+
+	stopped := make(chan struct{})
+	go func() {
+		th.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned while the function was running")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop didn't return after the function did")
+	}
+
+	th.Trigger()
+	c.Advance(time.Second)
+	expectNoCall(t, started)
+}
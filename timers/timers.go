@@ -2,24 +2,35 @@ package main
 
 import (
 	"fmt"
+	"testing"
 	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+	"github.com/yanw2/go-by-example/timers/debounce"
+	"github.com/yanw2/go-by-example/timers/wheel"
 )
 
 func main() {
+	run(clock.Real())
+	limit(clock.Real())
+	timeouts(clock.Real())
+}
+
+func run(clk clock.Clock) {
 	// Timers represent a single event in the future. You tell the timer how long you want to wait,
 	// and it provides a channel that will be notified at that time. This timer will wait 2 seconds.
-	timer1 := time.NewTimer(2 * time.Second)
+	timer1 := clk.NewTimer(2 * time.Second)
 
-	// The `<-timer1.C` blocks on the timer's channel C until it sends a value indicating that the
+	// The `<-timer1.Chan()` blocks on the timer's channel until it sends a value indicating that the
 	// timer fired.
-	<-timer1.C
+	<-timer1.Chan()
 	fmt.Println("Timer 1 fired")
 
 	// If you just wanted to wait, you could have used `timer.Sleep`. One reason a timer may be useful
 	// is that you can cancel the timer before it fires.
-	timer2 := time.NewTimer(time.Second)
+	timer2 := clk.NewTimer(time.Second)
 	go func() {
-		<-timer2.C
+		<-timer2.Chan()
 		fmt.Println("Timer 2 fired")
 	}()
 	stop2 := timer2.Stop()
@@ -28,5 +39,87 @@ func main() {
 	}
 
 	// Give the timer enough time to fire, if it ever was giong to, to show it is in fact stopped.
-	time.Sleep(2 * time.Second)
+	clk.Sleep(2 * time.Second)
+}
+
+// Timers are the building blocks for limiting how often something happens. The `debounce` package
+// offers two common patterns. A `Debouncer` waits until a burst of triggers has gone quiet before it
+// runs its function, and a `Throttler` runs its function at most once per interval however often it is
+// triggered.
+func limit(clk clock.Clock) {
+	start := clk.Now()
+	elapsed := func() time.Duration {
+		return clk.Now().Sub(start).Round(10 * time.Millisecond)
+	}
+
+	// Five triggers 20ms apart form a single burst, so the debounced function runs once, 100ms after
+	// the last trigger.
+	d := debounce.NewDebouncer(100*time.Millisecond, func() {
+		fmt.Println("debounced call after", elapsed())
+	}, debounce.WithClock(clk))
+	for i := 0; i < 5; i++ {
+		d.Trigger()
+		clk.Sleep(20 * time.Millisecond)
+	}
+	clk.Sleep(200 * time.Millisecond)
+	d.Stop()
+
+	// The throttled function runs straight away on the first trigger, and then at most once every
+	// 100ms while triggers keep arriving.
+	start = clk.Now()
+	t := debounce.NewThrottler(100*time.Millisecond, func() {
+		fmt.Println("throttled call after", elapsed())
+	}, debounce.WithClock(clk))
+	for i := 0; i < 12; i++ {
+		t.Trigger()
+		clk.Sleep(20 * time.Millisecond)
+	}
+	clk.Sleep(200 * time.Millisecond)
+	t.Stop()
+}
+
+// Allocating a timer per timeout gets expensive when there are hundreds of thousands of them, such as
+// one per open connection. A timing wheel from the `wheel` package keeps timeouts in buckets and
+// advances them all with a single ticker instead, firing each on the first tick after its deadline.
+func timeouts(clk clock.Clock) {
+	w := wheel.New(10*time.Millisecond, wheel.WithClock(clk))
+	defer w.Stop()
+
+	fired := make(chan string, 2)
+	w.AfterFunc(50*time.Millisecond, func() { fired <- "timeout 1" })
+	t2 := w.AfterFunc(100*time.Millisecond, func() { fired <- "timeout 2" })
+	if t2.Cancel() {
+		fmt.Println("timeout 2 cancelled")
+	}
+	fmt.Println(<-fired, "fired")
+	fmt.Println("pending timeouts:", w.Len())
+
+	// Most connection timeouts never fire: they are cancelled once the connection does its work. We
+	// benchmark scheduling and then cancelling timeouts a minute out, first with a timer each and then
+	// on a wheel.
+	noop := func() {}
+	std := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		timers := make([]*time.Timer, b.N)
+		for i := range timers {
+			timers[i] = time.AfterFunc(time.Minute, noop)
+		}
+		for _, t := range timers {
+			t.Stop()
+		}
+	})
+	wheeled := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		w := wheel.New(time.Millisecond)
+		defer w.Stop()
+		timers := make([]*wheel.Timer, b.N)
+		for i := range timers {
+			timers[i] = w.AfterFunc(time.Minute, noop)
+		}
+		for _, t := range timers {
+			t.Cancel()
+		}
+	})
+	fmt.Println("time.AfterFunc: ", std, std.MemString())
+	fmt.Println("wheel.AfterFunc:", wheeled, wheeled.MemString())
 }
@@ -0,0 +1,232 @@
+// Package wheel provides a hierarchical timing wheel for scheduling large numbers of timeouts. Unlike
+// the timers example, which allocates a runtime timer per timeout, a wheel keeps timeouts in buckets
+// and advances them all with a single ticker, trading precision (timeouts fire on a tick boundary) for
+// cheap scheduling and cancellation.
+package wheel
+
+import (
+	"sync"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+// Each level of the wheel has 1<<slotBits slots. A slot on level i covers 1<<(slotBits*i) ticks, so
+// with 6 levels of 64 slots the wheel spans 64^6 ticks, over two years at a 1ms resolution. Timeouts
+// further out than that are parked on the top level until they come into range.
+const (
+	slotBits = 6
+	numSlots = 1 << slotBits
+	slotMask = numSlots - 1
+	levels   = 6
+)
+
+// Option configures a Wheel.
+type Option func(*Wheel)
+
+// WithClock sets the clock whose ticker drives the wheel. The default is the system clock.
+func WithClock(clk clock.Clock) Option {
+	return func(w *Wheel) {
+		w.clock = clk
+	}
+}
+
+// Timer is a timeout scheduled on a wheel.
+type Timer struct {
+	w       *Wheel
+	expires uint64
+	fn      func()
+
+	// The fields below are guarded by the wheel's mutex. slot is nil once the timer has fired or been
+	// cancelled.
+	slot       *slot
+	prev, next *Timer
+}
+
+// slot is a doubly linked list of timers, so that a timer can be removed in constant time.
+type slot struct {
+	head *Timer
+}
+
+func (s *slot) push(t *Timer) {
+	t.slot = s
+	t.prev = nil
+	t.next = s.head
+	if s.head != nil {
+		s.head.prev = t
+	}
+	s.head = t
+}
+
+func (s *slot) remove(t *Timer) {
+	if t.prev != nil {
+		t.prev.next = t.next
+	} else {
+		s.head = t.next
+	}
+	if t.next != nil {
+		t.next.prev = t.prev
+	}
+	t.slot, t.prev, t.next = nil, nil, nil
+}
+
+// take empties the slot and returns its timers.
+func (s *slot) take() *Timer {
+	head := s.head
+	s.head = nil
+	return head
+}
+
+// Wheel schedules timeouts at a fixed tick resolution. It is safe for concurrent use.
+type Wheel struct {
+	tick  time.Duration
+	clock clock.Clock
+	start time.Time
+
+	mu      sync.Mutex
+	current uint64
+	slots   [levels][numSlots]slot
+	count   int
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// New starts a wheel that advances once every tick. Timeouts fire on the first tick at or after their
+// deadline, so they may run up to one tick late.
+func New(tick time.Duration, opts ...Option) *Wheel {
+	if tick <= 0 {
+		panic("wheel: non-positive tick")
+	}
+	w := &Wheel{
+		tick:  tick,
+		clock: clock.Real(),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.start = w.clock.Now()
+	go w.run()
+	return w
+}
+
+// AfterFunc schedules f to run in its own goroutine once d has elapsed, like time.AfterFunc. The
+// returned Timer can be used to cancel it.
+func (w *Wheel) AfterFunc(d time.Duration, f func()) *Timer {
+	// Tick n is processed once n ticks have passed since the start, so the timer belongs on the first
+	// tick at or after its deadline. That is counted from the clock rather than from w.current, which
+	// lags behind the clock by up to a tick.
+	deadline := w.clock.Now().Sub(w.start) + max(d, 0)
+	expires := uint64((deadline + w.tick - 1) / w.tick)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// The wheel only looks ahead of the tick it last processed.
+	expires = max(expires, w.current+1)
+	t := &Timer{w: w, expires: expires, fn: f}
+	w.addLocked(t)
+	w.count++
+	return t
+}
+
+// addLocked puts t in the slot of the lowest level whose span covers the time left until it expires.
+func (w *Wheel) addLocked(t *Timer) {
+	delta := t.expires - w.current
+	expires := t.expires
+	level := 0
+	for level < levels-1 && delta >= 1<<(slotBits*(level+1)) {
+		level++
+	}
+	if level == levels-1 && delta >= 1<<(slotBits*levels) {
+		// Too far out for the wheel: park it in the top level slot that is cascaded last, and it
+		// will be placed again when that happens.
+		expires = w.current + 1<<(slotBits*levels) - 1
+	}
+	idx := (expires >> (slotBits * level)) & slotMask
+	w.slots[level][idx].push(t)
+}
+
+// Cancel stops the timer from firing. It returns false if the timer has already fired or been
+// cancelled.
+func (t *Timer) Cancel() bool {
+	w := t.w
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if t.slot == nil {
+		return false
+	}
+	t.slot.remove(t)
+	w.count--
+	return true
+}
+
+// Len returns the number of pending timeouts.
+func (w *Wheel) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.count
+}
+
+func (w *Wheel) run() {
+	defer close(w.done)
+	ticker := w.clock.NewTicker(w.tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.Chan():
+			// Tickers drop ticks when the receiver falls behind, so work out from the clock how
+			// many ticks are due rather than counting the ones we received.
+			w.advance(uint64(now.Sub(w.start) / w.tick))
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// advance moves the wheel forward to the target tick, firing every timer that expires on the way.
+func (w *Wheel) advance(target uint64) {
+	w.mu.Lock()
+	var expired []func()
+	for w.current < target {
+		w.current++
+
+		// When the lower bits of the tick wrap around to zero, the next slot of the level above
+		// comes into range of the level below; move its timers down.
+		for level := 1; level < levels; level++ {
+			shift := slotBits * level
+			if w.current&(1<<shift-1) != 0 {
+				break
+			}
+			idx := (w.current >> shift) & slotMask
+			for t := w.slots[level][idx].take(); t != nil; {
+				next := t.next
+				w.addLocked(t)
+				t = next
+			}
+		}
+
+		for t := w.slots[0][w.current&slotMask].take(); t != nil; {
+			next := t.next
+			t.slot, t.prev, t.next = nil, nil, nil
+			w.count--
+			expired = append(expired, t.fn)
+			t = next
+		}
+	}
+	w.mu.Unlock()
+
+	for _, f := range expired {
+		go f()
+	}
+}
+
+// Stop stops the wheel's ticker. Pending timeouts never fire. It is safe to call Stop more than once.
+func (w *Wheel) Stop() {
+	w.once.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+}
@@ -0,0 +1,90 @@
+package wheel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+// The wheel runs callbacks on their own goroutines after its ticker fires, so the tests give them a
+// moment of real time to run.
+func assertNotFired(t *testing.T, fired <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-fired:
+		t.Fatal("timeout fired early")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func assertFired(t *testing.T, fired <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("timeout did not fire")
+	}
+}
+
+func newFakeWheel(t *testing.T, tick time.Duration) (*Wheel, *clock.Fake) {
+	fake := clock.NewFake(time.Now())
+	w := New(tick, WithClock(fake))
+	t.Cleanup(w.Stop)
+	// Wait for the wheel's ticker to exist, or the first ticks would be missed.
+	fake.BlockUntil(1)
+	return w, fake
+}
+
+func TestAfterFuncBetweenTicksNeverFiresEarly(t *testing.T) {
+	w, fake := newFakeWheel(t, 10*time.Millisecond)
+
+	// Part way through a tick, a 10ms timeout is due at 19ms, so it belongs to the tick at 20ms rather
+	// than the one at 10ms.
+	fake.Advance(9 * time.Millisecond)
+	fired := make(chan struct{})
+	w.AfterFunc(10*time.Millisecond, func() { close(fired) })
+
+	fake.Advance(time.Millisecond)
+	assertNotFired(t, fired)
+	fake.Advance(9 * time.Millisecond)
+	assertNotFired(t, fired)
+	fake.Advance(time.Millisecond)
+	assertFired(t, fired)
+}
+
+func TestAfterFuncCascadesFromHigherLevels(t *testing.T) {
+	w, fake := newFakeWheel(t, time.Millisecond)
+
+	// 100ms is past the first level's 64 slots, so the timeout starts on the second level and is
+	// moved down as the wheel turns.
+	fired := make(chan struct{})
+	w.AfterFunc(100*time.Millisecond, func() { close(fired) })
+	for i := 0; i < 99; i++ {
+		fake.Advance(time.Millisecond)
+	}
+	assertNotFired(t, fired)
+	fake.Advance(time.Millisecond)
+	assertFired(t, fired)
+	if n := w.Len(); n != 0 {
+		t.Fatalf("Len = %d, want 0", n)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	w, fake := newFakeWheel(t, time.Millisecond)
+
+	fired := make(chan struct{})
+	tm := w.AfterFunc(5*time.Millisecond, func() { close(fired) })
+	if !tm.Cancel() {
+		t.Fatal("Cancel of a pending timeout = false, want true")
+	}
+	if tm.Cancel() {
+		t.Fatal("second Cancel = true, want false")
+	}
+	if n := w.Len(); n != 0 {
+		t.Fatalf("Len = %d, want 0", n)
+	}
+	fake.Advance(10 * time.Millisecond)
+	assertNotFired(t, fired)
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
+
+	"github.com/yanw2/go-by-example/errors/errs"
 )
 
 // This is the function we'll run in every goroutine. Note that a WaitGroup must be passed to functions
@@ -29,4 +33,38 @@ func main() {
 
 	// Block until the WaitGroup counter goes back to 0; all the workers notified they're done.
 	wg.Wait()
+
+	collect()
+}
+
+// A WaitGroup says when the goroutines are done, but not whether they succeeded. An
+// `errs.Collector` gathers their errors, each with the input that failed, and can be given a limit
+// after which the remaining work is abandoned.
+func collect() {
+	inputs := []string{"1", "two", "3", "four", "five", "6"}
+	c := errs.NewCollector[string](2)
+
+	var wg sync.WaitGroup
+	for _, in := range inputs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Once the collector is full, there is no point starting more work.
+			select {
+			case <-c.Full():
+				return
+			default:
+			}
+			_, err := strconv.Atoi(in)
+			c.Add(in, err)
+		}()
+	}
+	wg.Wait()
+
+	// `Err` joins the errors in the same way as `errors.Join`, so `errors.Is` and `errors.As` see
+	// every one of them.
+	err := c.Err()
+	fmt.Println(err)
+	fmt.Println("syntax error:", errors.Is(err, strconv.ErrSyntax))
+	fmt.Println("failures recorded:", len(c.Failures()))
 }
@@ -0,0 +1,280 @@
+// Package pool turns the worker-pools example into a reusable pool: a set of workers receive typed
+// jobs from a priority queue, and every job produces a Result carrying either its value or its
+// error. The number of workers can be changed at runtime, either directly or by an autoscaler.
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yanw2/go-by-example/clock"
+	"github.com/yanw2/go-by-example/panic/supervise"
+)
+
+// ErrClosed is returned by Submit once the pool has been shut down.
+var ErrClosed = errors.New("pool: closed")
+
+// Func is the work performed for each job. The context is cancelled if the pool is shut down before
+// in-flight work has drained.
+type Func[J, R any] func(ctx context.Context, job J) (R, error)
+
+// Result is the outcome of a single job. Err is non-nil if the job failed, in which case Value is
+// whatever the Func returned alongside the error. A Func that panics fails with a
+// *supervise.PanicError rather than crashing the program.
+type Result[J, R any] struct {
+	Job   J
+	Value R
+	Err   error
+}
+
+// Stats is a snapshot of the pool's counters. Queued jobs have been submitted but not yet picked up
+// by a worker, Done jobs finished without error and Failed jobs returned an error or expired. Expired
+// counts just the jobs that failed with ErrExpired.
+type Stats struct {
+	Workers int
+	Queued  int64
+	Running int64
+	Done    int64
+	Failed  int64
+	Expired int64
+}
+
+// Option configures a Pool.
+type Option func(*config)
+
+type config struct {
+	workers   int
+	queueSize int
+	clock     clock.Clock
+	autoscale *Autoscale
+}
+
+// WithWorkers sets the number of workers. The default is 1.
+func WithWorkers(n int) Option {
+	return func(c *config) {
+		c.workers = n
+	}
+}
+
+// WithQueueSize sets how many jobs may wait for a worker before Submit blocks. The same size is used
+// to buffer the results channel. The default, and the minimum, is 1.
+func WithQueueSize(n int) Option {
+	return func(c *config) {
+		c.queueSize = n
+	}
+}
+
+// Pool runs jobs of type J on a set of workers, producing results of type R. Results must be received
+// from the Results channel, otherwise workers block once its buffer is full.
+type Pool[J, R any] struct {
+	fn     Func[J, R]
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// wmu guards the set of workers and closed. closing is closed by Shutdown, which tells Submit to
+	// give up and the dispatcher to stop accepting jobs. submit itself is never closed, so a Submit
+	// blocked on a full queue holds no lock that Shutdown has to wait for.
+	wmu     sync.Mutex
+	closed  bool
+	closing chan struct{}
+
+	submit    chan item[J]
+	jobs      chan item[J]
+	queueSize int
+	results   chan Result[J, R]
+	workers   []*worker
+	nworker   atomic.Int64
+	wg        sync.WaitGroup
+	done      chan struct{}
+
+	clock     clock.Clock
+	autoscale *Autoscale
+
+	queued  atomic.Int64
+	running atomic.Int64
+	ok      atomic.Int64
+	failed  atomic.Int64
+	expired atomic.Int64
+}
+
+// New starts a pool that runs fn for each submitted job.
+func New[J, R any](fn Func[J, R], opts ...Option) *Pool[J, R] {
+	c := config{workers: 1, queueSize: 1, clock: clock.Real()}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.workers < 1 {
+		c.workers = 1
+	}
+	if c.queueSize < 1 {
+		c.queueSize = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool[J, R]{
+		fn:        fn,
+		ctx:       ctx,
+		cancel:    cancel,
+		closing:   make(chan struct{}),
+		submit:    make(chan item[J]),
+		jobs:      make(chan item[J]),
+		queueSize: c.queueSize,
+		results:   make(chan Result[J, R], c.queueSize),
+		done:      make(chan struct{}),
+		clock:     c.clock,
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.dispatch()
+	}()
+	p.growLocked(c.workers)
+	if c.autoscale != nil {
+		a := c.autoscale.normalize(c.workers)
+		p.autoscale = &a
+		go p.autoscaleLoop()
+	}
+
+	// Once the dispatcher has drained the queue and every worker has exited, nothing else will be sent
+	// on results.
+	go func() {
+		p.wg.Wait()
+		close(p.results)
+		close(p.done)
+	}()
+	return p
+}
+
+// worker is the bookkeeping for one worker goroutine. Closing quit retires the worker once it has
+// finished its current job.
+type worker struct {
+	quit       chan struct{}
+	busy       atomic.Bool
+	lastActive atomic.Int64
+}
+
+func (p *Pool[J, R]) work(w *worker) {
+	defer p.wg.Done()
+	for {
+		// Check quit on its own first so that a retired worker doesn't pick up another job just
+		// because one happens to be ready too.
+		select {
+		case <-w.quit:
+			return
+		default:
+		}
+
+		select {
+		case <-w.quit:
+			return
+		case it, ok := <-p.jobs:
+			if !ok {
+				return
+			}
+			// The deadline is checked again here in case it passed while the job was being handed
+			// over.
+			if it.expired(p.clock.Now()) {
+				p.expire(it)
+				continue
+			}
+			// The worker is marked idle before the result is sent, so once a caller has received
+			// every result all workers already count as idle.
+			w.busy.Store(true)
+			r := p.run(it.job)
+			w.lastActive.Store(p.clock.Now().UnixNano())
+			w.busy.Store(false)
+			p.results <- r
+		}
+	}
+}
+
+func (p *Pool[J, R]) run(j J) Result[J, R] {
+	p.queued.Add(-1)
+	p.running.Add(1)
+	var v R
+	err := supervise.Catch(func() error {
+		var err error
+		v, err = p.fn(p.ctx, j)
+		return err
+	})
+	if err != nil {
+		p.failed.Add(1)
+	} else {
+		p.ok.Add(1)
+	}
+	p.running.Add(-1)
+	return Result[J, R]{Job: j, Value: v, Err: err}
+}
+
+// Submit queues a job, blocking while the queue is full. It returns ErrClosed if the pool has been
+// shut down, or ctx.Err() if ctx is done before the job could be queued. The options set the job's
+// priority and deadline.
+func (p *Pool[J, R]) Submit(ctx context.Context, job J, opts ...SubmitOption) error {
+	it := item[J]{job: job}
+	for _, opt := range opts {
+		opt(&it.jobOptions)
+	}
+
+	// Check closing on its own first, so that a Submit after Shutdown fails even if the dispatcher
+	// happens to be ready to take the job.
+	select {
+	case <-p.closing:
+		return ErrClosed
+	default:
+	}
+
+	p.queued.Add(1)
+	select {
+	case p.submit <- it:
+		return nil
+	case <-p.closing:
+		p.queued.Add(-1)
+		return ErrClosed
+	case <-ctx.Done():
+		p.queued.Add(-1)
+		return ctx.Err()
+	}
+}
+
+// Results returns the channel on which job results are delivered. It is closed after Shutdown once
+// all queued and in-flight jobs have finished.
+func (p *Pool[J, R]) Results() <-chan Result[J, R] {
+	return p.results
+}
+
+// Shutdown stops the pool from accepting new jobs and waits for queued and in-flight jobs to finish.
+// If ctx is done first, the context passed to running jobs is cancelled and ctx.Err() is returned;
+// the remaining jobs still drain in the background and Results is closed once they have.
+func (p *Pool[J, R]) Shutdown(ctx context.Context) error {
+	p.wmu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.closing)
+	}
+	p.wmu.Unlock()
+
+	select {
+	case <-p.done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of the pool's counters. Each counter is read atomically, but the snapshot
+// as a whole may mix values from slightly different moments while jobs are running.
+func (p *Pool[J, R]) Stats() Stats {
+	return Stats{
+		Workers: int(p.nworker.Load()),
+		Queued:  p.queued.Load(),
+		Running: p.running.Load(),
+		Done:    p.ok.Load(),
+		Failed:  p.failed.Load(),
+		Expired: p.expired.Load(),
+	}
+}
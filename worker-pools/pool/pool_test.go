@@ -0,0 +1,61 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestShutdownHonorsContextWhileSubmitIsBlocked(t *testing.T) {
+	release := make(chan struct{})
+	p := New(func(ctx context.Context, j int) (int, error) {
+		<-release
+		return j, nil
+	})
+	go func() {
+		for range p.Results() {
+		}
+	}()
+
+	// One job runs and one fills the queue, so the third Submit blocks.
+	submitN(t, p, 2)
+	waitFor(t, "the queue to fill", func() bool { return p.Stats().Running == 1 })
+	blocked := make(chan error, 1)
+	go func() { blocked <- p.Submit(context.Background(), 3) }()
+	waitFor(t, "the third Submit to block", func() bool { return p.Stats().Queued == 2 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- p.Shutdown(ctx) }()
+
+	select {
+	case err := <-shutdown:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Shutdown = %v, want context.DeadlineExceeded", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown didn't return once its context was done")
+	}
+
+	select {
+	case err := <-blocked:
+		if !errors.Is(err, ErrClosed) {
+			t.Fatalf("blocked Submit = %v, want ErrClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("blocked Submit didn't return after Shutdown")
+	}
+	if err := p.Submit(context.Background(), 4); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Submit after Shutdown = %v, want ErrClosed", err)
+	}
+
+	close(release)
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("second Shutdown = %v, want nil", err)
+	}
+	if got := p.Stats().Done; got != 2 {
+		t.Fatalf("Done = %d, want 2", got)
+	}
+}
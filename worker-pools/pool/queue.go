@@ -0,0 +1,172 @@
+package pool
+
+import (
+	"container/heap"
+	"errors"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+// ErrExpired is the error reported for a job whose deadline passed before a worker could start it.
+var ErrExpired = errors.New("pool: job deadline exceeded")
+
+// SubmitOption configures a single job passed to Submit.
+type SubmitOption func(*jobOptions)
+
+type jobOptions struct {
+	priority int
+	deadline time.Time
+}
+
+// WithPriority sets the priority of a job. Workers always take the waiting job with the highest
+// priority; jobs with equal priority run in the order they were submitted. The default is 0.
+func WithPriority(priority int) SubmitOption {
+	return func(o *jobOptions) {
+		o.priority = priority
+	}
+}
+
+// WithDeadline sets the time by which a worker must start the job. A job still waiting at its
+// deadline is not run but reported as failed with ErrExpired.
+func WithDeadline(deadline time.Time) SubmitOption {
+	return func(o *jobOptions) {
+		o.deadline = deadline
+	}
+}
+
+// item is a queued job along with its options. seq records the submission order to break ties
+// between jobs of equal priority.
+type item[J any] struct {
+	job J
+	jobOptions
+	seq uint64
+}
+
+func (it item[J]) expired(now time.Time) bool {
+	return !it.deadline.IsZero() && !now.Before(it.deadline)
+}
+
+// jobHeap implements heap.Interface, ordering items by descending priority and then by submission.
+type jobHeap[J any] []item[J]
+
+func (h jobHeap[J]) Len() int { return len(h) }
+
+func (h jobHeap[J]) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h jobHeap[J]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *jobHeap[J]) Push(x any) { *h = append(*h, x.(item[J])) }
+
+func (h *jobHeap[J]) Pop() any {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = item[J]{}
+	*h = old[:n-1]
+	return it
+}
+
+// dispatch is the goroutine that owns the queue, in the style of the stateful-goroutines example.
+// It accepts submitted jobs while there is room, always offers the highest-priority job to the
+// workers, and reports jobs whose deadline has passed as expired instead of handing them out. Once
+// the pool is closing and the queue is empty it closes jobs so that the workers exit.
+//
+// A timer set to the earliest deadline in the queue wakes the dispatcher when a job expires, so that
+// the job is reported and its place in the queue freed even while every worker is busy.
+func (p *Pool[J, R]) dispatch() {
+	var (
+		q       jobHeap[J]
+		seq     uint64
+		submit  = p.submit
+		closing = p.closing
+		timer   clock.Timer
+	)
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for {
+		now := p.clock.Now()
+		next := p.expireDue(&q, now)
+
+		var expiry <-chan time.Time
+		if !next.IsZero() {
+			if timer == nil {
+				timer = p.clock.NewTimer(next.Sub(now))
+			} else {
+				timer.Reset(next.Sub(now))
+			}
+			expiry = timer.Chan()
+		} else if timer != nil {
+			timer.Stop()
+		}
+
+		if submit == nil && q.Len() == 0 {
+			close(p.jobs)
+			return
+		}
+
+		in := submit
+		if q.Len() >= p.queueSize {
+			in = nil
+		}
+		var (
+			out chan<- item[J]
+			top item[J]
+		)
+		if q.Len() > 0 {
+			out = p.jobs
+			top = q[0]
+		}
+
+		select {
+		case <-closing:
+			submit, closing = nil, nil
+		case it := <-in:
+			it.seq = seq
+			seq++
+			heap.Push(&q, it)
+		case out <- top:
+			heap.Pop(&q)
+		case <-expiry:
+		}
+	}
+}
+
+// expireDue reports and removes every job in q whose deadline has passed, and returns the earliest
+// deadline of the jobs left, or the zero time if none of them has one. The heap is ordered by
+// priority rather than deadline, so the whole queue has to be checked.
+func (p *Pool[J, R]) expireDue(q *jobHeap[J], now time.Time) (next time.Time) {
+	kept := (*q)[:0]
+	for _, it := range *q {
+		if it.expired(now) {
+			p.expire(it)
+			continue
+		}
+		if !it.deadline.IsZero() && (next.IsZero() || it.deadline.Before(next)) {
+			next = it.deadline
+		}
+		kept = append(kept, it)
+	}
+	if len(kept) < len(*q) {
+		clear((*q)[len(kept):])
+		*q = kept
+		heap.Init(q)
+	}
+	return next
+}
+
+// expire reports a job that was not started before its deadline.
+func (p *Pool[J, R]) expire(it item[J]) {
+	p.queued.Add(-1)
+	p.failed.Add(1)
+	p.expired.Add(1)
+	p.results <- Result[J, R]{Job: it.job, Err: ErrExpired}
+}
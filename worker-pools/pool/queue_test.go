@@ -0,0 +1,158 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+// gatedPool returns a pool with one worker whose jobs block until the returned channel is closed. The
+// test receives the results itself; anything left is drained when it ends.
+func gatedPool(t *testing.T, fake *clock.Fake, queueSize int) (*Pool[string, string], chan struct{}) {
+	t.Helper()
+	release := make(chan struct{})
+	p := New(func(ctx context.Context, j string) (string, error) {
+		<-release
+		return j, nil
+	}, WithQueueSize(queueSize), WithClock(fake))
+	t.Cleanup(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+		go func() {
+			for range p.Results() {
+			}
+		}()
+		p.Shutdown(context.Background())
+	})
+	return p, release
+}
+
+// blockUntil waits for n timers on the fake clock, failing the test instead of hanging if they never
+// appear.
+func blockUntil(t *testing.T, fake *clock.Fake, n int) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		fake.BlockUntil(n)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for %d timers on the clock", n)
+	}
+}
+
+func receiveResult(t *testing.T, p *Pool[string, string]) Result[string, string] {
+	t.Helper()
+	select {
+	case r := <-p.Results():
+		return r
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a result")
+		return Result[string, string]{}
+	}
+}
+
+func TestExpiredJobIsReportedWhileWorkersAreBusy(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	p, release := gatedPool(t, fake, 1)
+
+	if err := p.Submit(context.Background(), "running"); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	waitFor(t, "the worker to start", func() bool { return p.Stats().Running == 1 })
+	if err := p.Submit(context.Background(), "late", WithDeadline(fake.Now().Add(time.Second))); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	// The dispatcher's deadline timer is the only thing waiting on the clock.
+	blockUntil(t, fake, 1)
+	fake.Advance(time.Second)
+
+	r := receiveResult(t, p)
+	if r.Job != "late" || !errors.Is(r.Err, ErrExpired) {
+		t.Fatalf("result = %+v, want job late failing with ErrExpired", r)
+	}
+	if s := p.Stats(); s.Queued != 0 || s.Expired != 1 || s.Running != 1 {
+		t.Fatalf("Stats = %+v, want Queued 0, Expired 1, Running 1", s)
+	}
+
+	// The expired job no longer holds the only place in the queue.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := p.Submit(ctx, "next"); err != nil {
+		t.Fatalf("Submit after expiry: %v", err)
+	}
+
+	close(release)
+	for _, want := range []string{"running", "next"} {
+		if r := receiveResult(t, p); r.Job != want || r.Err != nil {
+			t.Fatalf("result = %+v, want job %s without error", r, want)
+		}
+	}
+}
+
+func TestExpiryFindsLowPriorityJobs(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	p, release := gatedPool(t, fake, 2)
+
+	if err := p.Submit(context.Background(), "running"); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	waitFor(t, "the worker to start", func() bool { return p.Stats().Running == 1 })
+	// The expiring job sits below a job without a deadline, not at the top of the heap.
+	if err := p.Submit(context.Background(), "urgent", WithPriority(1)); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if err := p.Submit(context.Background(), "late", WithDeadline(fake.Now().Add(time.Second))); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	blockUntil(t, fake, 1)
+	fake.Advance(time.Second)
+	if r := receiveResult(t, p); r.Job != "late" || !errors.Is(r.Err, ErrExpired) {
+		t.Fatalf("result = %+v, want job late failing with ErrExpired", r)
+	}
+
+	close(release)
+	for _, want := range []string{"running", "urgent"} {
+		if r := receiveResult(t, p); r.Job != want || r.Err != nil {
+			t.Fatalf("result = %+v, want job %s without error", r, want)
+		}
+	}
+}
+
+func TestJobsRunInPriorityOrder(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	p, release := gatedPool(t, fake, 4)
+
+	if err := p.Submit(context.Background(), "first"); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	waitFor(t, "the worker to start", func() bool { return p.Stats().Running == 1 })
+
+	// Jobs of equal priority keep the order they were submitted in.
+	for _, j := range []struct {
+		name     string
+		priority int
+	}{{"low", 0}, {"high 1", 2}, {"medium", 1}, {"high 2", 2}} {
+		if err := p.Submit(context.Background(), j.name, WithPriority(j.priority)); err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+	}
+	waitFor(t, "the jobs to be queued", func() bool { return p.Stats().Queued == 4 })
+
+	close(release)
+	for _, want := range []string{"first", "high 1", "high 2", "medium", "low"} {
+		if r := receiveResult(t, p); r.Job != want {
+			t.Fatalf("result for job %q, want %q", r.Job, want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package pool
+
+import (
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+// WithClock sets the clock used to track idle workers and drive the autoscaler. The default is the
+// system clock.
+func WithClock(clk clock.Clock) Option {
+	return func(c *config) {
+		c.clock = clk
+	}
+}
+
+// Autoscale configures the autoscaler. Every Interval it adds a worker if more than QueueThreshold
+// jobs are waiting, up to MaxWorkers. Otherwise it retires workers that have been idle for at least
+// IdleTimeout, down to MinWorkers. A zero IdleTimeout never retires workers.
+type Autoscale struct {
+	MinWorkers     int
+	MaxWorkers     int
+	QueueThreshold int
+	IdleTimeout    time.Duration
+	Interval       time.Duration
+}
+
+// normalize fills in defaults so that 1 <= MinWorkers <= MaxWorkers, with MaxWorkers at least the
+// initial number of workers.
+func (a Autoscale) normalize(workers int) Autoscale {
+	if a.MinWorkers < 1 {
+		a.MinWorkers = 1
+	}
+	a.MaxWorkers = max(a.MaxWorkers, a.MinWorkers, workers)
+	if a.Interval <= 0 {
+		a.Interval = time.Second
+	}
+	return a
+}
+
+// WithAutoscale enables the autoscaler with the given settings.
+func WithAutoscale(a Autoscale) Option {
+	return func(c *config) {
+		c.autoscale = &a
+	}
+}
+
+// Resize changes the number of workers to n, which is raised to 1 if smaller. Retired workers finish
+// the job they are running first. If the autoscaler is enabled it may change the size again later.
+func (p *Pool[J, R]) Resize(n int) error {
+	n = max(n, 1)
+
+	p.wmu.Lock()
+	defer p.wmu.Unlock()
+	if p.closed {
+		return ErrClosed
+	}
+	if d := n - len(p.workers); d > 0 {
+		p.growLocked(d)
+	} else {
+		for _, w := range p.workers[n:] {
+			close(w.quit)
+		}
+		clear(p.workers[n:])
+		p.workers = p.workers[:n]
+		p.nworker.Store(int64(n))
+	}
+	return nil
+}
+
+func (p *Pool[J, R]) growLocked(n int) {
+	now := p.clock.Now().UnixNano()
+	for i := 0; i < n; i++ {
+		w := &worker{quit: make(chan struct{})}
+		w.lastActive.Store(now)
+		p.workers = append(p.workers, w)
+		p.wg.Add(1)
+		go p.work(w)
+	}
+	p.nworker.Store(int64(len(p.workers)))
+}
+
+// Autoscale runs a single round of the autoscaler immediately, using the pool's clock. The autoscaler
+// calls it on every tick; calling it directly is mostly useful together with a fake clock. It does
+// nothing if the autoscaler is not enabled.
+func (p *Pool[J, R]) Autoscale() {
+	a := p.autoscale
+	if a == nil {
+		return
+	}
+
+	p.wmu.Lock()
+	defer p.wmu.Unlock()
+	if p.closed {
+		return
+	}
+
+	if p.queued.Load() > int64(a.QueueThreshold) {
+		if len(p.workers) < a.MaxWorkers {
+			p.growLocked(1)
+		}
+		return
+	}
+
+	if a.IdleTimeout <= 0 {
+		return
+	}
+	now := p.clock.Now().UnixNano()
+	kept := p.workers[:0]
+	for i, w := range p.workers {
+		idle := !w.busy.Load() && time.Duration(now-w.lastActive.Load()) >= a.IdleTimeout
+		if idle && len(kept)+len(p.workers)-i > a.MinWorkers {
+			close(w.quit)
+			continue
+		}
+		kept = append(kept, w)
+	}
+	clear(p.workers[len(kept):])
+	p.workers = kept
+	p.nworker.Store(int64(len(kept)))
+}
+
+func (p *Pool[J, R]) autoscaleLoop() {
+	t := p.clock.NewTicker(p.autoscale.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-t.Chan():
+			p.Autoscale()
+		}
+	}
+}
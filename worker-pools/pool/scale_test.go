@@ -0,0 +1,145 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+)
+
+// waitFor polls cond until it holds, failing the test if it doesn't within a second. Workers pick up
+// jobs on their own goroutines, so the tests can't know exactly when that has happened.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// blockingPool returns a pool whose jobs block until release is closed, and a cleanup function that
+// releases them and shuts the pool down.
+func blockingPool(t *testing.T, fake *clock.Fake, workers int, a Autoscale) (*Pool[int, int], func()) {
+	release := make(chan struct{})
+	p := New(func(ctx context.Context, j int) (int, error) {
+		<-release
+		return j, nil
+	}, WithWorkers(workers), WithQueueSize(16), WithClock(fake), WithAutoscale(a))
+	go func() {
+		for range p.Results() {
+		}
+	}()
+	return p, func() {
+		close(release)
+		if err := p.Shutdown(context.Background()); err != nil {
+			t.Errorf("Shutdown: %v", err)
+		}
+	}
+}
+
+func submitN(t *testing.T, p *Pool[int, int], n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := p.Submit(context.Background(), i); err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+	}
+}
+
+func TestAutoscaleGrowsUpToMaxWorkers(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	p, stop := blockingPool(t, fake, 1, Autoscale{
+		MaxWorkers:     3,
+		QueueThreshold: 1,
+		Interval:       time.Hour,
+	})
+	defer stop()
+
+	// One job keeps the only worker busy, and the next one waits: the queue is at the threshold but
+	// not past it.
+	submitN(t, p, 2)
+	waitFor(t, "the first job to start", func() bool { return p.Stats().Running == 1 })
+	p.Autoscale()
+	if got := p.Stats().Workers; got != 1 {
+		t.Fatalf("workers at the threshold = %d, want 1", got)
+	}
+
+	// Past the threshold, each round adds one worker, which takes a job from the queue.
+	submitN(t, p, 3)
+	for want := 2; want <= 3; want++ {
+		p.Autoscale()
+		if got := p.Stats().Workers; got != want {
+			t.Fatalf("workers = %d, want %d", got, want)
+		}
+		waitFor(t, "the new worker to take a job", func() bool { return p.Stats().Running == int64(want) })
+	}
+
+	// Two jobs are still queued, past the threshold, but the pool is at MaxWorkers.
+	if got := p.Stats().Queued; got != 2 {
+		t.Fatalf("queued = %d, want 2", got)
+	}
+	p.Autoscale()
+	if got := p.Stats().Workers; got != 3 {
+		t.Fatalf("workers at MaxWorkers = %d, want 3", got)
+	}
+}
+
+func TestAutoscaleRetiresIdleWorkersDownToMinWorkers(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	p, stop := blockingPool(t, fake, 4, Autoscale{
+		MinWorkers:  2,
+		MaxWorkers:  4,
+		IdleTimeout: time.Minute,
+		Interval:    time.Hour,
+	})
+	defer stop()
+
+	// One worker stays busy, so it is never idle.
+	submitN(t, p, 1)
+	waitFor(t, "the job to start", func() bool { return p.Stats().Running == 1 })
+
+	fake.Advance(59 * time.Second)
+	p.Autoscale()
+	if got := p.Stats().Workers; got != 4 {
+		t.Fatalf("workers before IdleTimeout = %d, want 4", got)
+	}
+
+	// Three workers are now idle, but retiring all of them would go below MinWorkers.
+	fake.Advance(time.Second)
+	p.Autoscale()
+	if got := p.Stats().Workers; got != 2 {
+		t.Fatalf("workers after IdleTimeout = %d, want 2", got)
+	}
+
+	fake.Advance(time.Hour - time.Minute)
+	p.Autoscale()
+	if got := p.Stats().Workers; got != 2 {
+		t.Fatalf("workers at MinWorkers = %d, want 2", got)
+	}
+}
+
+func TestAutoscaleRunsOnEveryInterval(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	p, stop := blockingPool(t, fake, 1, Autoscale{
+		MaxWorkers:     2,
+		QueueThreshold: 0,
+		Interval:       time.Second,
+	})
+	defer stop()
+
+	submitN(t, p, 2)
+	waitFor(t, "the first job to start", func() bool { return p.Stats().Running == 1 })
+
+	// Nothing changes until the autoscaler's ticker fires. Wait for the ticker to exist before moving
+	// the time, or the tick would be missed.
+	fake.BlockUntil(1)
+	if got := p.Stats().Workers; got != 1 {
+		t.Fatalf("workers before the interval = %d, want 1", got)
+	}
+	fake.Advance(time.Second)
+	waitFor(t, "the autoscaler to add a worker", func() bool { return p.Stats().Workers == 2 })
+}
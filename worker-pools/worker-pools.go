@@ -1,30 +1,38 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"time"
+
+	"github.com/yanw2/go-by-example/clock"
+	"github.com/yanw2/go-by-example/worker-pools/pool"
 )
 
 // Here's the worker, of which we'll run several concurrent instances. These workers will receive
 // work on the jobs channel and send the corresponding results on results. We'll sleep a second
-// per job to simulate an expensive task.
-func worker(id int, jobs <-chan int, results chan<- int) {
+// per job on the given clock to simulate an expensive task.
+func worker(clk clock.Clock, id int, jobs <-chan int, results chan<- int) {
 	for j := range jobs {
 		fmt.Println("worker", id, " started job", j)
-		time.Sleep(time.Second)
+		clk.Sleep(time.Second)
 		fmt.Println("worker", id, " finished job", j)
 		results <- j * 2
 	}
 }
 
 func main() {
+	// The workers wait on a `clock.Clock`. Here we give them the real one; the autoscaling demo further
+	// down uses a fake one.
+	clk := clock.Real()
+
 	const numJobs = 5
 	jobs := make(chan int, numJobs)
 	results := make(chan int, numJobs)
 
 	// This start up 3 workers, initially blocked because there are no jobs yet.
 	for w := 1; w <= 3; w++ {
-		go worker(w, jobs, results)
+		go worker(clk, w, jobs, results)
 	}
 
 	// Here we send 5 jobs and then close that channel to indicate that's all the work we have.
@@ -38,4 +46,101 @@ func main() {
 	for a := 1; a <= numJobs; a++ {
 		<-results
 	}
+
+	// The `pool` package packages this pattern up for reuse. Each job produces a `Result` carrying
+	// either its value or its error, so failing jobs are reported instead of lost.
+	p := pool.New(func(ctx context.Context, j int) (int, error) {
+		clk.Sleep(100 * time.Millisecond)
+		if j%4 == 0 {
+			return 0, fmt.Errorf("can't work with %d", j)
+		}
+		return j * 2, nil
+	}, pool.WithWorkers(3), pool.WithQueueSize(numJobs))
+
+	// Submit the jobs from a separate goroutine and then shut the pool down. `Shutdown` stops new
+	// submissions and waits for the queued and in-flight jobs to drain, after which `Results` is closed.
+	go func() {
+		for j := 1; j <= numJobs; j++ {
+			p.Submit(context.Background(), j)
+		}
+		p.Shutdown(context.Background())
+	}()
+
+	for r := range p.Results() {
+		if r.Err != nil {
+			fmt.Println("job", r.Job, "failed:", r.Err)
+		} else {
+			fmt.Println("job", r.Job, "result", r.Value)
+		}
+	}
+	fmt.Printf("%+v\n", p.Stats())
+
+	// The number of workers can also change at runtime. `Resize` grows or shrinks the pool directly,
+	// while the autoscaler adds a worker whenever too many jobs are waiting and retires workers that
+	// have been idle for too long. Here we drive the autoscaler with a `clock.Fake` so that we don't
+	// have to wait for real time to pass. We also run rounds of the autoscaler ourselves with
+	// `Autoscale` rather than waiting for its ticker.
+	fake := clock.NewFake(time.Now())
+	release := make(chan struct{})
+	sp := pool.New(func(ctx context.Context, j int) (int, error) {
+		<-release
+		return j * 2, nil
+	}, pool.WithQueueSize(numJobs), pool.WithClock(fake), pool.WithAutoscale(pool.Autoscale{
+		MinWorkers:     1,
+		MaxWorkers:     3,
+		QueueThreshold: 1,
+		IdleTimeout:    10 * time.Second,
+	}))
+
+	for j := 1; j <= numJobs; j++ {
+		sp.Submit(context.Background(), j)
+	}
+	for i := 0; i < 3; i++ {
+		sp.Autoscale()
+		fmt.Println("workers after scaling up:", sp.Stats().Workers)
+	}
+
+	close(release)
+	for a := 1; a <= numJobs; a++ {
+		<-sp.Results()
+	}
+	fake.Advance(11 * time.Second)
+	sp.Autoscale()
+	fmt.Println("workers after idling:", sp.Stats().Workers)
+
+	sp.Resize(4)
+	fmt.Println("workers after resize:", sp.Stats().Workers)
+	sp.Shutdown(context.Background())
+
+	// Jobs can also carry a priority and a deadline. Workers always take the waiting job with the highest
+	// priority, and a job whose deadline passes before a worker gets to it fails with `pool.ErrExpired`
+	// rather than being run late. Our single worker is kept busy with the first job while the others queue up.
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	pp := pool.New(func(ctx context.Context, j string) (string, error) {
+		if j == "first" {
+			close(started)
+			<-unblock
+		}
+		return "ran " + j, nil
+	}, pool.WithQueueSize(numJobs), pool.WithClock(fake))
+
+	ctx := context.Background()
+	pp.Submit(ctx, "first")
+	<-started
+	pp.Submit(ctx, "low", pool.WithPriority(1))
+	pp.Submit(ctx, "high", pool.WithPriority(5))
+	pp.Submit(ctx, "urgent", pool.WithPriority(10), pool.WithDeadline(fake.Now().Add(time.Second)))
+	fake.Advance(2 * time.Second)
+	close(unblock)
+
+	go pp.Shutdown(ctx)
+	for r := range pp.Results() {
+		if r.Err != nil {
+			fmt.Println(r.Job, "failed:", r.Err)
+		} else {
+			fmt.Println(r.Value)
+		}
+	}
+	fmt.Printf("%+v\n", pp.Stats())
 }